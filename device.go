@@ -0,0 +1,110 @@
+//go:build cgo
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log/slog"
+	"math"
+
+	"gocv.io/x/gocv"
+
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+	"github.com/benoitmasson/qrcode-demo/qrcode"
+)
+
+// scanDevice captures frames from the given webcam device and decodes the QR-codes found,
+// until the device is closed or Esc key is pressed.
+func scanDevice(deviceID int, options qrcode.Options) {
+	webcam, err := gocv.OpenVideoCapture(deviceID)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error opening video capture device %d: %v", deviceID, err))
+		return
+	}
+	defer webcam.Close()
+
+	window := gocv.NewWindow("QR-code decoder")
+	defer window.Close()
+
+	// pre-allocate matrices once and for all
+	img, imgWithMiniCode := gocv.NewMat(), gocv.NewMat()
+	defer img.Close()
+	defer imgWithMiniCode.Close()
+
+	first := true
+	var width, height, fps int
+	var collector partsCollector
+	slog.Info(fmt.Sprintf("Start reading device: %v", deviceID))
+	for {
+		if ok := webcam.Read(&img); !ok {
+			slog.Error(fmt.Sprintf("Device closed: %v", deviceID))
+			return
+		}
+		if img.Empty() {
+			continue
+		}
+
+		if first {
+			width = img.Cols()
+			height = img.Rows()
+			fps = int(math.Round(webcam.Get(gocv.VideoCaptureFPS)))
+			slog.Info(fmt.Sprintf("[%s] %dx%d, %dfps", img.Type(), width, height, fps))
+			first = false
+		}
+
+		img, results := scanCodes(&img, &imgWithMiniCode, options)
+
+		window.IMShow(img)
+		if window.WaitKey(1) == 27 {
+			break
+		}
+
+		anyComplete := false
+		for _, result := range results {
+			if message, complete := collector.collect(result); complete {
+				slog.Warn(fmt.Sprintf("QR-code message at %s is: '\033[1m%s\033[0m'", position(result.Points), message))
+				anyComplete = true
+			}
+		}
+		if anyComplete {
+			fmt.Println()
+			webcam.Grab(3 * fps) // drop frames and sleep for 3 seconds
+		}
+	}
+}
+
+// scanCodes extracts all the QR-codes from the given image, then decodes them.
+// If at least one is decoded, returns a new image with miniature QR-codes in the top-left corner and all the outlines
+// drawn (in red for the decoded QR-codes, in orange for the others), along with the decoding results.
+// Otherwise, returns the original image.
+func scanCodes(img, imgWithMiniCode *gocv.Mat, options qrcode.Options) (gocv.Mat, []qrcode.Result) {
+	codes, corners, inverted, err := qrcode.DetectAllDots(*img, imgWithMiniCode, options)
+	if err != nil {
+		slog.Debug(fmt.Sprintf("No valid QR-code found in video frame: %v", err))
+		return *img, nil
+	}
+	slog.Info(fmt.Sprintf("Dots of %d QR-code(s) scanned successfully, proceed", len(codes)))
+
+	results := make([]qrcode.Result, 0, len(codes))
+	for i, softDots := range codes {
+		result, err := qrcode.DecodeDetected(softDots, inverted[i])
+		if err != nil {
+			slog.Warn(fmt.Sprintf("QR-code at %s: %v", position(corners[i]), err))
+			detect.OutlineQRCode(imgWithMiniCode, corners[i], color.RGBA{255, 165, 0, 255}, 5)
+			continue
+		}
+		result.Points = corners[i]
+		logResult(result)
+
+		printQRCode(result.Dots)
+		detect.OutlineQRCode(imgWithMiniCode, corners[i], color.RGBA{255, 0, 0, 255}, 5)
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return *img, nil
+	}
+	// success
+	return *imgWithMiniCode, results
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/benoitmasson/qrcode-demo/internal/decode"
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+	"github.com/benoitmasson/qrcode-demo/internal/encode"
+	"github.com/benoitmasson/qrcode-demo/internal/render"
+)
+
+// generateQRCode encodes the given text into a QR-code, prints it on the console,
+// and exports it to the given file if not empty.
+func generateQRCode(text, outputPath string) error {
+	dots, err := encode.Encode(text, decode.ErrorCorrectionLevelMedium)
+	if err != nil {
+		return err
+	}
+	printQRCode(dots)
+
+	if outputPath == "" {
+		return nil
+	}
+	return exportQRCode(outputPath, dots)
+}
+
+// exportQRCode writes the QR-code to the given file, in PNG, SVG or PDF format depending on the file extension.
+func exportQRCode(path string, dots detect.QRCode) error {
+	var write func(f *os.File) error
+	switch extension := strings.ToLower(filepath.Ext(path)); extension {
+	case ".png":
+		write = func(f *os.File) error { return render.PNG(f, dots, render.DefaultOptions) }
+	case ".svg":
+		write = func(f *os.File) error { return render.SVG(f, dots, render.DefaultOptions) }
+	case ".pdf":
+		write = func(f *os.File) error { return render.PDF(f, dots, render.DefaultOptions) }
+	default:
+		return fmt.Errorf("unsupported output format %q, expected .png, .svg or .pdf", extension)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
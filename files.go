@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log/slog"
+	"os"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+
+	"github.com/benoitmasson/qrcode-demo/qrcode"
+)
+
+// decodeFiles decodes the QR-codes found in each of the given image files (PNG, JPEG, GIF, BMP or WebP),
+// and prints the decoded messages on the standard output, one line per QR-code. When a file holds several
+// QR-codes, the position of each of them is printed as well.
+// If outputPath is not empty, the decoded QR-codes are exported to this file (the last one wins).
+// It returns false if at least one file does not yield any QR-code.
+func decodeFiles(paths []string, outputPath string, options qrcode.Options) bool {
+	ok := true
+	for _, path := range paths {
+		results, err := decodeFile(path, options)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			ok = false
+			continue
+		}
+		for _, result := range results {
+			logResult(result)
+			if len(results) > 1 {
+				fmt.Printf("%s at %s: %s\n", path, position(result.Points), result.Message)
+			} else {
+				fmt.Printf("%s: %s\n", path, result.Message)
+			}
+		}
+
+		if outputPath != "" {
+			if err := exportQRCode(outputPath, results[len(results)-1].Dots); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+				ok = false
+			}
+		}
+	}
+	return ok
+}
+
+// decodeFile reads the given image file, then decodes the QR-codes it contains.
+func decodeFile(path string, options qrcode.Options) ([]qrcode.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, format, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	slog.Debug(fmt.Sprintf("Read %s image, %dx%d", format, img.Bounds().Dx(), img.Bounds().Dy()))
+
+	return qrcode.DecodeAllWithOptions(img, options)
+}
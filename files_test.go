@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/benoitmasson/qrcode-demo/internal/decode"
+	"github.com/benoitmasson/qrcode-demo/internal/encode"
+	"github.com/benoitmasson/qrcode-demo/internal/render"
+	"github.com/benoitmasson/qrcode-demo/qrcode"
+)
+
+func TestDecodeFile(t *testing.T) {
+	dir := t.TempDir()
+	text := "https://github.com/benoitmasson/qrcode-demo"
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedError bool
+	}{
+		{
+			name: "PNG file",
+			path: newPNGFile(t, dir, "code.png", text),
+		},
+		{
+			name:          "missing file",
+			path:          filepath.Join(dir, "missing.png"),
+			expectedError: true,
+		},
+		{
+			name:          "not an image",
+			path:          newFile(t, dir, "code.txt", text),
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, err := decodeFile(test.path, qrcode.DefaultOptions)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got %d QR-codes", len(results))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != 1 || results[0].Message != text {
+				t.Errorf("expected a single QR-code with message %q but got %v", text, results)
+			}
+		})
+	}
+}
+
+func TestDecodeFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := newPNGFile(t, dir, "first.png", "HELLO WORLD")
+	second := newPNGFile(t, dir, "second.png", "https://github.com/benoitmasson/qrcode-demo")
+
+	var ok bool
+	output := captureStdout(t, func() {
+		ok = decodeFiles([]string{first, second}, "", qrcode.DefaultOptions)
+	})
+	if !ok {
+		t.Errorf("expected all files to be decoded")
+	}
+	expected := first + ": HELLO WORLD\n" + second + ": https://github.com/benoitmasson/qrcode-demo\n"
+	if output != expected {
+		t.Errorf("expected output %q but got %q", expected, output)
+	}
+
+	// a missing file is reported, other files are decoded nonetheless
+	output = captureStdout(t, func() {
+		ok = decodeFiles([]string{filepath.Join(dir, "missing.png"), first}, "", qrcode.DefaultOptions)
+	})
+	if ok {
+		t.Errorf("expected missing file to be reported")
+	}
+	if expected := first + ": HELLO WORLD\n"; output != expected {
+		t.Errorf("expected output %q but got %q", expected, output)
+	}
+}
+
+// newPNGFile encodes the text, and writes the QR-code as a PNG file with the given name in dir.
+// It returns the path of the file.
+func newPNGFile(t *testing.T, dir, name, text string) string {
+	t.Helper()
+
+	dots, err := encode.Encode(text, decode.ErrorCorrectionLevelMedium)
+	if err != nil {
+		t.Fatalf("failed to encode text: %v", err)
+	}
+	var png bytes.Buffer
+	if err := render.PNG(&png, dots, render.DefaultOptions); err != nil {
+		t.Fatalf("failed to render QR-code: %v", err)
+	}
+	return newFile(t, dir, name, png.String())
+}
+
+// newFile writes the given contents to a file with the given name in dir, and returns its path.
+func newFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+// captureStdout runs f, and returns what it prints on the standard output.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		output <- string(b)
+	}()
+	f()
+	w.Close()
+	return <-output
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/benoitmasson/qrcode-demo/qrcode"
+)
+
+// videoFrames reads the frames of a video one at a time, and decodes the QR-codes they hold.
+type videoFrames interface {
+	// next reads the next frame, and returns the QR-codes decoded in it along with its timestamp in the video.
+	// It returns false once all frames have been read, and an error if the frame cannot be decoded.
+	next() ([]qrcode.Result, time.Duration, bool, error)
+}
+
+// printVideoMessages reads all the frames, and prints every distinct message decoded on the standard output,
+// with the frame number, timestamp and position where it was first found. Several QR-codes may be decoded
+// in the same frame, and messages split with structured append are printed once all their parts are collected.
+// It returns an error if no QR-code was found.
+func printVideoMessages(frames videoFrames) error {
+	seen := make(map[string]bool)
+	var collector partsCollector
+	for frame := 1; ; frame++ {
+		results, timestamp, ok, err := frames.next()
+		if !ok {
+			break
+		}
+		if err != nil {
+			slog.Debug(fmt.Sprintf("Frame %d: %v", frame, err))
+			continue
+		}
+
+		for _, result := range results {
+			message, complete := collector.collect(result)
+			if !complete || seen[message] {
+				continue
+			}
+			seen[message] = true
+			logResult(result)
+			fmt.Printf("frame %d (%s) at %s: %s\n", frame, timestamp, position(result.Points), message)
+		}
+	}
+
+	if len(seen) == 0 {
+		return errors.New("no QR-code found in video")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/benoitmasson/qrcode-demo/internal/decode"
+	"github.com/benoitmasson/qrcode-demo/qrcode"
+)
+
+// fakeFrames returns the given results for each frame in turn, one frame every 40ms.
+type fakeFrames struct {
+	frames []fakeFrame
+	read   int
+}
+
+// fakeFrame is the outcome of reading one frame.
+type fakeFrame struct {
+	results []qrcode.Result
+	err     error
+}
+
+func (f *fakeFrames) next() ([]qrcode.Result, time.Duration, bool, error) {
+	if f.read == len(f.frames) {
+		return nil, 0, false, nil
+	}
+	frame := f.frames[f.read]
+	timestamp := time.Duration(f.read) * 40 * time.Millisecond
+	f.read++
+	return frame.results, timestamp, true, frame.err
+}
+
+func TestPrintVideoMessages(t *testing.T) {
+	hello := qrcode.Result{Message: "HELLO", Points: []image.Point{{0, 0}, {20, 0}, {20, 20}, {0, 20}}}
+	world := qrcode.Result{Message: "WORLD"}
+	errNoCode := errors.New("no valid QR-code found in image")
+
+	parity := byte('a' ^ 'b' ^ 'c')
+	part1 := qrcode.Result{
+		Segments:         []qrcode.Segment{{Mode: decode.ByteMode, Length: 2, Text: "ab", Bytes: []byte("ab")}},
+		StructuredAppend: &qrcode.StructuredAppend{Index: 0, Total: 2, Parity: parity},
+	}
+	part2 := qrcode.Result{
+		Segments:         []qrcode.Segment{{Mode: decode.ByteMode, Length: 1, Text: "c", Bytes: []byte("c")}},
+		StructuredAppend: &qrcode.StructuredAppend{Index: 1, Total: 2, Parity: parity},
+	}
+
+	tests := []struct {
+		name           string
+		frames         []fakeFrame
+		expectedOutput string
+		expectedError  bool
+	}{
+		{
+			name: "messages printed once",
+			frames: []fakeFrame{
+				{results: []qrcode.Result{hello}},
+				{results: []qrcode.Result{hello}},
+				{err: errNoCode},
+				{results: []qrcode.Result{world, hello}},
+			},
+			expectedOutput: "frame 1 (0s) at (10, 10): HELLO\nframe 4 (120ms) at (?, ?): WORLD\n",
+		},
+		{
+			name: "structured append",
+			frames: []fakeFrame{
+				{results: []qrcode.Result{part2}},
+				{err: errNoCode},
+				{results: []qrcode.Result{part2, part1}},
+				{results: []qrcode.Result{part1}},
+			},
+			expectedOutput: "frame 3 (80ms) at (?, ?): abc\n",
+		},
+		{
+			name:          "no QR-code",
+			frames:        []fakeFrame{{err: errNoCode}, {}, {err: errNoCode}},
+			expectedError: true,
+		},
+		{
+			name:          "empty video",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error
+			output := captureStdout(t, func() {
+				err = printVideoMessages(&fakeFrames{frames: test.frames})
+			})
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if output != test.expectedOutput {
+				t.Errorf("expected output %q but got %q", test.expectedOutput, output)
+			}
+		})
+	}
+}
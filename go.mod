@@ -7,4 +7,6 @@ toolchain go1.24.1
 require (
 	github.com/colin-davis/reedSolomon v0.0.0-20171106220123-4bffafb75357
 	gocv.io/x/gocv v0.41.0
+	golang.org/x/image v0.25.0
+	golang.org/x/text v0.25.0
 )
@@ -0,0 +1,121 @@
+package decode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Inspired from https://www.qrcode.com/en/about/standards.html (ISO/IEC 18004, section 8: Structured Append)
+
+// StructuredAppend is the header of a QR-code which holds one part of a message split across
+// several QR-codes (up to 16).
+type StructuredAppend struct {
+	// Index is the position of the QR-code in the sequence, starting from 0.
+	Index uint
+	// Total is the number of QR-codes in the sequence.
+	Total uint
+	// Parity is the XOR of all the bytes of the full message, identical in all QR-codes of the sequence.
+	Parity byte
+}
+
+// GetStructuredAppend extracts the structured append header, when found at the beginning of the contents.
+// After the mode indicator (4 bits), it is made of the QR-code index (4 bits), the total number of QR-codes
+// minus 1 (4 bits), and the parity byte.
+// The header is returned along with the remaining bits, and whether the header was found.
+// If not, the bits are returned untouched.
+func GetStructuredAppend(bits []bool) (StructuredAppend, []bool, bool) {
+	if len(bits) < 4+4+4+8 {
+		return StructuredAppend{}, bits, false
+	}
+	if mode, _ := GetMode(bits); mode != StructuredAppendMode {
+		return StructuredAppend{}, bits, false
+	}
+
+	header := StructuredAppend{
+		Index:  uint(BitsToUint16(bits[4:8])),
+		Total:  uint(BitsToUint16(bits[8:12])) + 1,
+		Parity: byte(BitsToUint16(bits[12:20])),
+	}
+	return header, bits[20:], true
+}
+
+// Sequence collects the parts of a message split across several QR-codes, in any order.
+type Sequence struct {
+	total  uint
+	parity byte
+	parts  [][]Segment // indexed by QR-code position, nil when not collected yet
+}
+
+// NewSequence returns an empty sequence, for the QR-codes matching the given header.
+func NewSequence(header StructuredAppend) *Sequence {
+	return &Sequence{
+		total:  header.Total,
+		parity: header.Parity,
+		parts:  make([][]Segment, header.Total),
+	}
+}
+
+// Matches returns whether the given header belongs to the sequence.
+func (s *Sequence) Matches(header StructuredAppend) bool {
+	return header.Total == s.total && header.Parity == s.parity && header.Index < s.total
+}
+
+// Add stores the segments of the QR-code with the given header in the sequence.
+// It returns whether the part was new, or an error if it does not belong to the sequence.
+func (s *Sequence) Add(header StructuredAppend, segments []Segment) (bool, error) {
+	if !s.Matches(header) {
+		return false, fmt.Errorf("QR-code %d/%d (parity %02x) does not belong to sequence of %d (parity %02x)",
+			header.Index+1, header.Total, header.Parity, s.total, s.parity)
+	}
+	if s.parts[header.Index] != nil {
+		return false, nil
+	}
+	s.parts[header.Index] = segments
+	return true, nil
+}
+
+// Collected returns the number of distinct parts collected so far.
+func (s *Sequence) Collected() uint {
+	collected := uint(0)
+	for _, part := range s.parts {
+		if part != nil {
+			collected++
+		}
+	}
+	return collected
+}
+
+// Total returns the number of parts in the sequence.
+func (s *Sequence) Total() uint {
+	return s.total
+}
+
+// Complete returns whether all the parts of the sequence have been collected.
+func (s *Sequence) Complete() bool {
+	return s.Collected() == s.total
+}
+
+// Message reassembles the full message from all the parts, in order, and checks its parity.
+// It fails if some parts are missing, or if the parity does not match.
+func (s *Sequence) Message() (string, error) {
+	if !s.Complete() {
+		return "", errors.New("missing parts in sequence")
+	}
+
+	var message strings.Builder
+	parity := byte(0)
+	for _, part := range s.parts {
+		for _, segment := range part {
+			message.WriteString(segment.Text)
+			for _, b := range segment.Bytes {
+				parity ^= b
+			}
+		}
+	}
+
+	if parity != s.parity {
+		return "", fmt.Errorf("parity mismatch: expected %02x but got %02x", s.parity, parity)
+	}
+	return message.String(), nil
+}
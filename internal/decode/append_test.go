@@ -0,0 +1,92 @@
+package decode
+
+import (
+	"testing"
+)
+
+func TestGetStructuredAppend(t *testing.T) {
+	type test struct {
+		name              string
+		bits              string
+		expectedFound     bool
+		expectedHeader    StructuredAppend
+		expectedRemaining int
+	}
+	tests := []test{
+		{
+			name:              "header found",
+			bits:              "0011" + "0001" + "0010" + "10100101" + "0100",
+			expectedFound:     true,
+			expectedHeader:    StructuredAppend{Index: 1, Total: 3, Parity: 0b10100101},
+			expectedRemaining: 4,
+		},
+		{
+			name:              "no header",
+			bits:              "0100" + "00000001" + "01100001" + "0000",
+			expectedFound:     false,
+			expectedRemaining: 24,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualHeader, remaining, found := GetStructuredAppend(bitsFromString(test.bits))
+			if found != test.expectedFound {
+				t.Errorf("expected found to equal %v but got %v", test.expectedFound, found)
+			}
+			if actualHeader != test.expectedHeader {
+				t.Errorf("expected header %+v but got %+v", test.expectedHeader, actualHeader)
+			}
+			if len(remaining) != test.expectedRemaining {
+				t.Errorf("expected %d remaining bits but got %d", test.expectedRemaining, len(remaining))
+			}
+		})
+	}
+}
+
+func TestSequence(t *testing.T) {
+	parity := byte('a' ^ 'b' ^ 'c' ^ '1' ^ '2')
+	part1 := []Segment{{Mode: ByteMode, Length: 2, Text: "ab", Bytes: []byte("ab")}}
+	part2 := []Segment{{Mode: ByteMode, Length: 1, Text: "c", Bytes: []byte("c")}, {Mode: NumericMode, Length: 2, Text: "12", Bytes: []byte("12")}}
+
+	sequence := NewSequence(StructuredAppend{Index: 1, Total: 2, Parity: parity})
+	if _, err := sequence.Add(StructuredAppend{Index: 1, Total: 3, Parity: parity}, part2); err == nil {
+		t.Errorf("expected an error when adding a part from another sequence")
+	}
+
+	added, err := sequence.Add(StructuredAppend{Index: 1, Total: 2, Parity: parity}, part2)
+	if err != nil || !added {
+		t.Errorf("expected part 2 to be added, got %v, %v", added, err)
+	}
+	added, err = sequence.Add(StructuredAppend{Index: 1, Total: 2, Parity: parity}, part2)
+	if err != nil || added {
+		t.Errorf("expected part 2 to be ignored the second time, got %v, %v", added, err)
+	}
+	if sequence.Complete() {
+		t.Errorf("expected sequence to be incomplete with %d/%d parts", sequence.Collected(), sequence.Total())
+	}
+	if _, err := sequence.Message(); err == nil {
+		t.Errorf("expected an error for incomplete sequence")
+	}
+
+	if _, err := sequence.Add(StructuredAppend{Index: 0, Total: 2, Parity: parity}, part1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !sequence.Complete() {
+		t.Errorf("expected sequence to be complete with %d/%d parts", sequence.Collected(), sequence.Total())
+	}
+	message, err := sequence.Message()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if message != "abc12" {
+		t.Errorf("expected %q but got %q", "abc12", message)
+	}
+
+	invalid := NewSequence(StructuredAppend{Index: 0, Total: 1, Parity: parity})
+	_, _ = invalid.Add(StructuredAppend{Index: 0, Total: 1, Parity: parity}, part1)
+	if _, err := invalid.Message(); err == nil {
+		t.Errorf("expected a parity error")
+	}
+}
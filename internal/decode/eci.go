@@ -0,0 +1,115 @@
+package decode
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Inspired from https://en.wikipedia.org/wiki/Extended_Channel_Interpretation
+
+// Charset is a character set, used to interpret Byte mode segments.
+type Charset struct {
+	// Name is the usual name of the character set.
+	Name     string
+	encoding encoding.Encoding
+}
+
+// DefaultCharset is the character set used for Byte mode segments when no ECI is present.
+var DefaultCharset = Charset{"ISO-8859-1", charmap.ISO8859_1}
+
+// shiftJISCharset is the character set used for Kanji mode segments.
+var shiftJISCharset = Charset{"Shift_JIS", japanese.ShiftJIS}
+
+// charsetByECI maps ECI assignment numbers to the corresponding character sets.
+// Source: https://github.com/zxing/zxing/blob/master/core/src/main/java/com/google/zxing/common/CharacterSetECI.java
+var charsetByECI = map[uint]Charset{
+	0:   {"Cp437", charmap.CodePage437},
+	1:   {"ISO-8859-1", charmap.ISO8859_1},
+	2:   {"Cp437", charmap.CodePage437},
+	3:   {"ISO-8859-1", charmap.ISO8859_1},
+	4:   {"ISO-8859-2", charmap.ISO8859_2},
+	5:   {"ISO-8859-3", charmap.ISO8859_3},
+	6:   {"ISO-8859-4", charmap.ISO8859_4},
+	7:   {"ISO-8859-5", charmap.ISO8859_5},
+	8:   {"ISO-8859-6", charmap.ISO8859_6},
+	9:   {"ISO-8859-7", charmap.ISO8859_7},
+	10:  {"ISO-8859-8", charmap.ISO8859_8},
+	11:  {"ISO-8859-9", charmap.ISO8859_9},
+	12:  {"ISO-8859-10", charmap.ISO8859_10},
+	13:  {"ISO-8859-11", charmap.Windows874}, // Windows-874 is a superset of ISO-8859-11
+	15:  {"ISO-8859-13", charmap.ISO8859_13},
+	16:  {"ISO-8859-14", charmap.ISO8859_14},
+	17:  {"ISO-8859-15", charmap.ISO8859_15},
+	18:  {"ISO-8859-16", charmap.ISO8859_16},
+	20:  shiftJISCharset,
+	21:  {"Windows-1250", charmap.Windows1250},
+	22:  {"Windows-1251", charmap.Windows1251},
+	23:  {"Windows-1252", charmap.Windows1252},
+	24:  {"Windows-1256", charmap.Windows1256},
+	25:  {"UTF-16BE", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+	26:  {"UTF-8", unicode.UTF8},
+	27:  {"US-ASCII", charmap.ISO8859_1}, // ASCII is a subset of ISO-8859-1
+	28:  {"Big5", traditionalchinese.Big5},
+	29:  {"GB2312", simplifiedchinese.GBK}, // GBK is a superset of GB2312
+	30:  {"EUC-KR", korean.EUCKR},
+	32:  {"GB18030", simplifiedchinese.GB18030},
+	170: {"US-ASCII", charmap.ISO8859_1},
+}
+
+// GetECI extracts the ECI assignment number from the header, after the ECI mode indicator (first 4 bits).
+// The assignment number is encoded on 1, 2 or 3 bytes, depending on the value of the first bits
+// (0xxxxxxx, 10xxxxxx xxxxxxxx or 110xxxxx xxxxxxxx xxxxxxxx).
+// The matching character set is returned, as well as the remaining bits, after the ECI designator.
+func GetECI(bits []bool) (uint, Charset, []bool, error) {
+	if len(bits) < 4+8 {
+		return 0, Charset{}, nil, errors.New("not enough bits in contents for ECI designator")
+	}
+	bits = bits[4:]
+
+	var nb int
+	switch {
+	case !bits[0]:
+		nb = 8
+	case !bits[1]:
+		nb = 16
+	case !bits[2]:
+		nb = 24
+	default:
+		return 0, Charset{}, nil, errors.New("invalid ECI designator")
+	}
+	if len(bits) < nb {
+		return 0, Charset{}, nil, errors.New("not enough bits in contents for ECI designator")
+	}
+
+	prefixLength := nb / 8 // leading bits "0", "10" or "110" are not part of the value
+	assignment := uint(0)
+	for _, bit := range bits[prefixLength:nb] {
+		assignment <<= 1
+		if bit {
+			assignment++
+		}
+	}
+
+	charset, ok := charsetByECI[assignment]
+	if !ok {
+		return 0, Charset{}, nil, fmt.Errorf("unsupported ECI assignment number %d", assignment)
+	}
+	return assignment, charset, bits[nb:], nil
+}
+
+// decodeCharset converts the given raw bytes, encoded with the given character set, to UTF-8 text.
+func decodeCharset(raw string, charset Charset) (string, error) {
+	text, err := charset.encoding.NewDecoder().String(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s characters: %w", charset.Name, err)
+	}
+	return text, nil
+}
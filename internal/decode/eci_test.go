@@ -0,0 +1,74 @@
+package decode
+
+import (
+	"testing"
+)
+
+func TestGetECI(t *testing.T) {
+	type test struct {
+		name               string
+		bits               string
+		expectedAssignment uint
+		expectedCharset    string
+		expectedRemaining  int
+		expectedError      bool
+	}
+	tests := []test{
+		{
+			name:               "1-byte designator",
+			bits:               "0111" + "00011010" + "0100",
+			expectedAssignment: 26,
+			expectedCharset:    "UTF-8",
+			expectedRemaining:  4,
+		},
+		{
+			name:               "2-byte designator",
+			bits:               "0111" + "10000000" + "10101010" + "0100",
+			expectedAssignment: 170,
+			expectedCharset:    "US-ASCII",
+			expectedRemaining:  4,
+		},
+		{
+			name:               "3-byte designator",
+			bits:               "0111" + "11000000" + "00000000" + "00010100",
+			expectedAssignment: 20,
+			expectedCharset:    "Shift_JIS",
+			expectedRemaining:  0,
+		},
+		{
+			name:          "invalid designator",
+			bits:          "0111" + "11100000" + "00000000" + "00000000",
+			expectedError: true,
+		},
+		{
+			name:          "truncated designator",
+			bits:          "0111" + "10000000",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualAssignment, actualCharset, remaining, err := GetECI(bitsFromString(test.bits))
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got ECI %d", actualAssignment)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if actualAssignment != test.expectedAssignment {
+				t.Errorf("expected assignment %d but got %d", test.expectedAssignment, actualAssignment)
+			}
+			if actualCharset.Name != test.expectedCharset {
+				t.Errorf("expected charset %s but got %s", test.expectedCharset, actualCharset.Name)
+			}
+			if len(remaining) != test.expectedRemaining {
+				t.Errorf("expected %d remaining bits but got %d", test.expectedRemaining, len(remaining))
+			}
+		})
+	}
+}
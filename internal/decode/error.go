@@ -1,8 +1,9 @@
 package decode
 
 import (
-	"errors"
+	"cmp"
 	"fmt"
+	"slices"
 
 	"github.com/colin-davis/reedSolomon"
 )
@@ -38,36 +39,168 @@ func init() {
 }
 
 // Correct applies the Reed-Solomon error correction algorithm to the given bits.
-// The corrected bits are returned upon success, or an error if the correction failed.
-// Interleaved content is not supported yet, and will return an error.
+// The corrected content bits are returned upon success (without ECC symbols), or an error if the correction failed.
+// When data is split in several blocks, the blocks are first de-interleaved, then each one of them is corrected
+// independently, and the contents of all blocks are finally concatenated back in order.
 //
 // This function uses http://github.com/colin-davis/reedSolomon for error correction.
 // See https://en.m.wikiversity.org/wiki/Reed%E2%80%93Solomon_codes_for_coders for further details
 // on how the algorithm works.
 func Correct(bits []bool, version uint, errorCorrectionLevel ErrorCorrectionLevel) ([]bool, error) {
+	return CorrectWithConfidence(bits, nil, 0, version, errorCorrectionLevel)
+}
+
+// CorrectAndCount applies the Reed-Solomon error correction algorithm to the given bits, as Correct does,
+// and returns the number of codewords which were corrected as well.
+func CorrectAndCount(bits []bool, version uint, errorCorrectionLevel ErrorCorrectionLevel) ([]bool, int, error) {
+	return correct(bits, nil, 0, version, errorCorrectionLevel)
+}
+
+// CorrectWithConfidence applies the Reed-Solomon error correction algorithm to the given bits, as Correct does,
+// knowing the confidence of the reading of each bit (from 0 to 1).
+// Reed-Solomon corrects errors at known positions (erasures) at half the cost of errors at unknown positions:
+// when a block cannot be corrected, it is corrected again with its unreliable codewords (those holding a bit
+// read with a confidence lower than minConfidence) passed as erasures, the least reliable ones first,
+// up to the number of ECC symbols minus 1. If confidences is nil, blocks are only corrected without erasures.
+func CorrectWithConfidence(bits []bool, confidences []float64, minConfidence float64, version uint, errorCorrectionLevel ErrorCorrectionLevel) ([]bool, error) {
+	correctedBits, _, err := correct(bits, confidences, minConfidence, version, errorCorrectionLevel)
+	return correctedBits, err
+}
+
+// correct implements CorrectWithConfidence, and returns the number of codewords corrected in all blocks as well.
+func correct(bits []bool, confidences []float64, minConfidence float64, version uint, errorCorrectionLevel ErrorCorrectionLevel) ([]bool, int, error) {
+	if version < 1 || version > 40 {
+		return bits, 0, fmt.Errorf("unable to correct message: invalid version %d", version)
+	}
 	blocksLayout := dataLayoutByVersionByErrorCorrectionLevel[version][errorCorrectionLevel]
-	if len(blocksLayout) != 1 || blocksLayout[0].numberOfBlocks != 1 {
-		// TODO: de-interleave error correction blocks and contents,
-		// in the following cases:
-		// - error correction level == low 		and version >= 6
-		// - error correction level == medium 	and version >= 4
-		// - error correction level == quartile and version >= 3
-		// - error correction level == high 	and version >= 3
-		// See https://www.thonky.com/qr-code-tutorial/error-correction-table
 
-		return bits, errors.New("unable to correct message: data is interleaved, not handled yet")
+	totalLength := 0
+	for _, layout := range blocksLayout {
+		totalLength += layout.numberOfBlocks * layout.totalBlockBytes
+	}
+	if len(bits) < totalLength*8 {
+		return bits, 0, fmt.Errorf("unable to correct message: expected %d bytes but got only %d bits", totalLength, len(bits))
 	}
-	totalLength, contentLength := blocksLayout[0].totalBlockBytes, blocksLayout[0].contentBlockBytes
 
-	contentInt := bitsToIntSlice(totalLength, bits)
+	blocks := deinterleave(bitsToIntSlice(totalLength, bits), blocksLayout)
+
+	var codewordConfidences []float64
+	var positions []block
+	if len(confidences) >= totalLength*8 {
+		codewordConfidences = bitsToCodewordConfidences(totalLength, confidences)
+		// de-interleave the codewords indices as well, to find out the position of each block codeword
+		indices := make([]int, totalLength)
+		for i := range indices {
+			indices[i] = i
+		}
+		positions = deinterleave(indices, blocksLayout)
+	}
 
-	numberECCSymbols := totalLength - contentLength
-	errorLocations := []int{}
-	correctedContent, _, err := reedSolomon.Decode(contentInt, numberECCSymbols, errorLocations)
+	correctedContent := make([]int, 0, totalLength)
+	corrections := 0
+	for i, block := range blocks {
+		correctedBlock, blockCorrections, err := correctBlock(block, nil)
+		if err != nil && codewordConfidences != nil {
+			// the decoder fails when all ECC symbols are spent on erasures, although it is theoretically possible
+			erasures := leastReliableCodewords(positions[i].codewords, codewordConfidences, minConfidence, block.numberECCSymbols-1)
+			if len(erasures) > 0 {
+				correctedBlock, blockCorrections, err = correctBlock(block, erasures)
+			}
+		}
+		if err != nil {
+			return bits, 0, fmt.Errorf("failed to correct message block %d/%d: %w", i+1, len(blocks), err)
+		}
+		correctedContent = append(correctedContent, correctedBlock...)
+		corrections += blockCorrections
+	}
+	return intSliceToBits(correctedContent), corrections, nil
+}
+
+// correctBlock applies the Reed-Solomon algorithm to the block, with the codewords at the given positions
+// in the block marked as erased. Returns the corrected content codewords, and the number of codewords
+// (content or ECC) which were corrected.
+func correctBlock(b block, erasures []int) ([]int, int, error) {
+	// the decoder alters the given codewords
+	correctedBlock, correctedECC, err := reedSolomon.Decode(slices.Clone(b.codewords), b.numberECCSymbols, erasures)
 	if err != nil {
-		return bits, fmt.Errorf("failed to correct message: %w", err)
+		return nil, 0, err
+	}
+	corrections := 0
+	for i, codeword := range append(slices.Clone(correctedBlock), correctedECC...) {
+		if codeword != b.codewords[i] {
+			corrections++
+		}
 	}
-	return intSliceToBits(correctedContent), nil
+	return correctedBlock, corrections, nil
+}
+
+// bitsToCodewordConfidences returns the confidence of each of the first "length" codewords:
+// the lowest confidence among its 8 bits.
+func bitsToCodewordConfidences(length int, confidences []float64) []float64 {
+	codewordConfidences := make([]float64, 0, length)
+	for i := 0; i < length*8; i += 8 {
+		codewordConfidences = append(codewordConfidences, slices.Min(confidences[i:i+8]))
+	}
+	return codewordConfidences
+}
+
+// leastReliableCodewords returns the positions in a block of the codewords whose confidence is lower than
+// minConfidence, the least reliable ones first, and no more than maxErasures of them.
+// positions holds the index in the full message of each codeword of the block.
+func leastReliableCodewords(positions []int, codewordConfidences []float64, minConfidence float64, maxErasures int) []int {
+	erasures := make([]int, 0, maxErasures)
+	for j, position := range positions {
+		if codewordConfidences[position] < minConfidence {
+			erasures = append(erasures, j)
+		}
+	}
+	slices.SortStableFunc(erasures, func(a, b int) int {
+		return cmp.Compare(codewordConfidences[positions[a]], codewordConfidences[positions[b]])
+	})
+	return erasures[:min(len(erasures), maxErasures)]
+}
+
+// block is a de-interleaved piece of data: its content codewords followed by its ECC codewords.
+type block struct {
+	codewords        []int
+	numberECCSymbols int
+}
+
+// deinterleave splits the given codewords into blocks, according to the given layout.
+// Codewords are interleaved as follows: first codeword of each content block, then second codeword of
+// each content block, and so on (shorter blocks come first and are skipped once exhausted),
+// then ECC codewords in the same fashion (all blocks have the same number of ECC codewords).
+// See https://www.thonky.com/qr-code-tutorial/structure-final-message
+func deinterleave(codewords []int, blocksLayout []dataLayout) []block {
+	blocks := make([]block, 0, len(blocksLayout))
+	maxContentLength := 0
+	for _, layout := range blocksLayout {
+		for range layout.numberOfBlocks {
+			blocks = append(blocks, block{
+				codewords:        make([]int, layout.contentBlockBytes, layout.totalBlockBytes),
+				numberECCSymbols: layout.totalBlockBytes - layout.contentBlockBytes,
+			})
+		}
+		maxContentLength = max(maxContentLength, layout.contentBlockBytes)
+	}
+
+	i := 0
+	for j := range maxContentLength {
+		for _, b := range blocks {
+			if j < len(b.codewords) {
+				b.codewords[j] = codewords[i]
+				i++
+			}
+		}
+	}
+	for range blocks[0].numberECCSymbols {
+		for k := range blocks {
+			blocks[k].codewords = append(blocks[k].codewords, codewords[i])
+			i++
+		}
+	}
+
+	return blocks
 }
 
 // bitsToIntSlice converts the first "length" bytes of a sequence of bits
@@ -111,6 +244,23 @@ type dataLayout struct {
 	contentBlockBytes int
 }
 
+// BlockSizes returns the number of content bytes of each block, in order, for the given version and
+// error correction level, as well as the number of ECC symbols (the same for all blocks).
+func BlockSizes(version uint, errorCorrectionLevel ErrorCorrectionLevel) ([]int, int) {
+	if version < 1 || version > 40 {
+		return nil, 0
+	}
+	blocksLayout := dataLayoutByVersionByErrorCorrectionLevel[version][errorCorrectionLevel]
+
+	contentBlockBytes := make([]int, 0, 4)
+	for _, layout := range blocksLayout {
+		for range layout.numberOfBlocks {
+			contentBlockBytes = append(contentBlockBytes, layout.contentBlockBytes)
+		}
+	}
+	return contentBlockBytes, blocksLayout[0].totalBlockBytes - blocksLayout[0].contentBlockBytes
+}
+
 // dataLayoutByVersionByErrorCorrectionLevel contains the data layout for each version and error correction level.
 // The value is a non-empty sequence of data layouts. The first layout describes the first blocks of data, and so on.
 // Content and ECC data is interleaved when the total number of blocks for a given (version, error correction level)
@@ -73,3 +73,250 @@ func TestIntSliceToBits(t *testing.T) {
 		})
 	}
 }
+
+func TestCorrect(t *testing.T) {
+	// version 5-H: 2 blocks of 33 bytes (11 content bytes) followed by 2 blocks of 34 bytes (12 content bytes), interleaved
+	interleaved := []int{
+		66, 151, 86, 23, 182, 70, 230, 38, 135, 135, 246, 54, 71, 86, 151, 246, 71, 34, 70, 70,
+		7, 230, 214, 82, 51, 54, 23, 214, 162, 246, 55, 70, 242, 210, 54, 86, 246, 246, 246, 214,
+		118, 38, 226, 240, 247, 236, 176, 15, 190, 42, 240, 14, 207, 23, 151, 59, 154, 216, 105, 57,
+		181, 146, 218, 16, 2, 94, 64, 7, 130, 221, 155, 244, 69, 71, 123, 112, 137, 191, 8, 161,
+		134, 244, 118, 160, 54, 68, 214, 219, 7, 89, 58, 39, 39, 109, 165, 225, 235, 134, 42, 206,
+		191, 47, 162, 138, 72, 183, 246, 183, 249, 188, 224, 121, 241, 187, 49, 213, 94, 41, 131, 225,
+		90, 174, 17, 82, 14, 42, 185, 39, 201, 67, 224, 65, 225, 150,
+	}
+	// "https://github.com/benoitmasson/qrcode-demo" in byte mode, padded
+	content := []int{
+		66, 182, 135, 71, 71, 7, 51, 162, 242, 246, 118, 151, 70, 135, 86, 34, 230, 54, 246, 210,
+		246, 38, 86, 230, 246, 151, 70, 214, 23, 55, 54, 246, 226, 247, 23, 38, 54, 246, 70, 82,
+		214, 70, 86, 214, 240, 236,
+	}
+
+	type test struct {
+		name          string
+		errors        map[int]int // position => wrong value
+		expectedError bool
+	}
+	tests := []test{
+		{
+			name: "no error",
+		},
+		{
+			name:   "errors in all blocks",
+			errors: map[int]int{0: 0, 5: 1, 10: 2, 47: 3, 100: 4, 133: 5},
+		},
+		{
+			name: "errors in content and ECC of the same block",
+			// positions 3, 7, 11, ... all belong to the 4th block, which can correct up to 11 errors
+			errors: map[int]int{3: 0, 7: 0, 11: 0, 15: 0, 19: 0, 23: 0, 27: 0, 31: 0, 35: 0, 39: 0, 43: 0},
+		},
+		{
+			name: "too many errors in one block",
+			// positions 0, 4, ..., 40 then 46, 50 all belong to the 1st block, which can correct up to 11 errors
+			errors:        map[int]int{0: 1, 4: 1, 8: 1, 12: 1, 16: 1, 20: 1, 24: 1, 28: 1, 32: 1, 36: 1, 40: 1, 46: 1, 50: 1},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			codewords := slices.Clone(interleaved)
+			for position, value := range test.errors {
+				codewords[position] = value
+			}
+			bits := append(intSliceToBits(codewords), false, false, false, false, false, false, false) // 7 remainder bits
+
+			actualBits, err := Correct(bits, 5, ErrorCorrectionLevelHigh)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if expectedBits := intSliceToBits(content); !slices.Equal(actualBits, expectedBits) {
+				t.Errorf("expected %v but got %v", expectedBits, actualBits)
+			}
+		})
+	}
+}
+
+// version5HCodewords are the codewords of a version 5-H QR-code: 2 blocks of 33 bytes (11 content bytes)
+// followed by 2 blocks of 34 bytes (12 content bytes), interleaved.
+var version5HCodewords = []int{
+	66, 151, 86, 23, 182, 70, 230, 38, 135, 135, 246, 54, 71, 86, 151, 246, 71, 34, 70, 70,
+	7, 230, 214, 82, 51, 54, 23, 214, 162, 246, 55, 70, 242, 210, 54, 86, 246, 246, 246, 214,
+	118, 38, 226, 240, 247, 236, 176, 15, 190, 42, 240, 14, 207, 23, 151, 59, 154, 216, 105, 57,
+	181, 146, 218, 16, 2, 94, 64, 7, 130, 221, 155, 244, 69, 71, 123, 112, 137, 191, 8, 161,
+	134, 244, 118, 160, 54, 68, 214, 219, 7, 89, 58, 39, 39, 109, 165, 225, 235, 134, 42, 206,
+	191, 47, 162, 138, 72, 183, 246, 183, 249, 188, 224, 121, 241, 187, 49, 213, 94, 41, 131, 225,
+	90, 174, 17, 82, 14, 42, 185, 39, 201, 67, 224, 65, 225, 150,
+}
+
+// version5HContent is the content of version5HCodewords:
+// "https://github.com/benoitmasson/qrcode-demo" in byte mode, padded.
+var version5HContent = []int{
+	66, 182, 135, 71, 71, 7, 51, 162, 242, 246, 118, 151, 70, 135, 86, 34, 230, 54, 246, 210,
+	246, 38, 86, 230, 246, 151, 70, 214, 23, 55, 54, 246, 226, 247, 23, 38, 54, 246, 70, 82,
+	214, 70, 86, 214, 240, 236,
+}
+
+// version5HBits returns the bits of version5HCodewords, with the given codewords replaced,
+// followed by the 7 remainder bits.
+func version5HBits(errors map[int]int) []bool {
+	codewords := slices.Clone(version5HCodewords)
+	for position, value := range errors {
+		codewords[position] = value
+	}
+	return append(intSliceToBits(codewords), false, false, false, false, false, false, false)
+}
+
+// flipCodewords returns the codewords of version5HCodewords at the given positions with all their bits flipped,
+// by position.
+func flipCodewords(positions ...int) map[int]int {
+	errors := make(map[int]int, len(positions))
+	for _, position := range positions {
+		errors[position] = version5HCodewords[position] ^ 0xff
+	}
+	return errors
+}
+
+// blockErrors are errors in 15 codewords of the 1st block (positions 0, 4, ..., 40 then 46, 50, ..., 58),
+// which can correct up to 11 errors, or up to 21 erasures.
+var blockErrors = flipCodewords(0, 4, 8, 12, 16, 20, 24, 28, 32, 36, 40, 46, 50, 54, 58)
+
+// version5HCorruptions are the errors in version5HCodewords shared by the correction tests.
+var version5HCorruptions = []struct {
+	name   string
+	errors map[int]int // position => wrong value
+	// tooMany is whether there are more errors than the ECC symbols can correct, without erasures.
+	tooMany bool
+}{
+	{
+		name: "no error",
+	},
+	{
+		name:   "errors in content and ECC of several blocks",
+		errors: flipCodewords(1, 6, 120),
+	},
+	{
+		name: "maximum correctable errors in one block",
+		// positions 3, 7, 11, ... all belong to the 4th block, which can correct up to 11 errors
+		errors: flipCodewords(3, 7, 11, 15, 19, 23, 27, 31, 35, 39, 43),
+	},
+	{
+		name:    "too many errors in one block",
+		errors:  blockErrors,
+		tooMany: true,
+	},
+}
+
+func TestCorrectAndCount(t *testing.T) {
+	for _, test := range version5HCorruptions {
+		t.Run(test.name, func(t *testing.T) {
+			actualBits, corrections, err := CorrectAndCount(version5HBits(test.errors), 5, ErrorCorrectionLevelHigh)
+			if test.tooMany {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if expectedBits := intSliceToBits(version5HContent); !slices.Equal(actualBits, expectedBits) {
+				t.Errorf("expected %v but got %v", expectedBits, actualBits)
+			}
+			if corrections != len(test.errors) {
+				t.Errorf("expected %d corrections but got %d", len(test.errors), corrections)
+			}
+		})
+	}
+}
+
+func TestCorrectWithConfidence(t *testing.T) {
+	type test struct {
+		name          string
+		errors        map[int]int     // position => wrong value
+		confidences   map[int]float64 // position => confidence of the codeword bits, 1 otherwise
+		expectedError bool
+	}
+	tests := make([]test, 0, len(version5HCorruptions)+5)
+	for _, corruption := range version5HCorruptions {
+		// erasures cost half as many ECC symbols as errors: all shared cases can be corrected once their errors are unreliable
+		confidences := make(map[int]float64, len(corruption.errors))
+		for position := range corruption.errors {
+			confidences[position] = 0.1
+		}
+		tests = append(tests, test{
+			name:        corruption.name + ", all errors unreliable",
+			errors:      corruption.errors,
+			confidences: confidences,
+		})
+	}
+	tests = append(tests, []test{
+		{
+			name:          "no confidence",
+			errors:        blockErrors,
+			expectedError: true,
+		},
+		{
+			name:   "some errors unreliable",
+			errors: blockErrors,
+			// 9 erasures and 6 errors cost 21 ECC symbols
+			confidences: map[int]float64{0: 0.1, 4: 0.1, 8: 0.1, 12: 0.1, 16: 0.1, 20: 0.1, 24: 0.1, 28: 0.1, 32: 0.1},
+		},
+		{
+			name:   "too few errors unreliable",
+			errors: blockErrors,
+			// 7 erasures and 8 errors cost 23 ECC symbols
+			confidences:   map[int]float64{0: 0.1, 4: 0.1, 8: 0.1, 12: 0.1, 16: 0.1, 20: 0.1, 24: 0.1},
+			expectedError: true,
+		},
+		{
+			name:   "correct codewords unreliable as well",
+			errors: blockErrors,
+			// only the 21 least reliable codewords are erased: the 15 errors and 6 correct codewords
+			confidences: map[int]float64{0: 0.1, 4: 0.1, 8: 0.1, 12: 0.1, 16: 0.1, 20: 0.1, 24: 0.1, 28: 0.1, 32: 0.1, 36: 0.1,
+				40: 0.1, 46: 0.1, 50: 0.1, 54: 0.1, 58: 0.1, 62: 0.4, 66: 0.4, 70: 0.4, 74: 0.4, 78: 0.4, 82: 0.4, 86: 0.4, 90: 0.4},
+		},
+	}...)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bits := version5HBits(test.errors)
+
+			var confidences []float64
+			if test.confidences != nil {
+				confidences = make([]float64, len(bits))
+				for i := range confidences {
+					confidences[i] = 1
+				}
+				for position, confidence := range test.confidences {
+					for i := range 8 {
+						confidences[8*position+i] = confidence
+					}
+				}
+			}
+
+			actualBits, err := CorrectWithConfidence(bits, confidences, 0.5, 5, ErrorCorrectionLevelHigh)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if expectedBits := intSliceToBits(version5HContent); !slices.Equal(actualBits, expectedBits) {
+				t.Errorf("expected %v but got %v", expectedBits, actualBits)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package decode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Inspired from https://www.gs1.org/standards/barcodes/2d and
+// https://ref.gs1.org/standards/genspecs/ (GS1 General Specifications, section 3: Application Identifiers)
+
+// GroupSeparator is the ASCII "GS" character, used to terminate variable-length GS1 element strings.
+const GroupSeparator = '\x1d'
+
+// FNC1 is the "function 1" indicator state, telling that the data follows a specific industry format.
+type FNC1 struct {
+	// Position is 1 for FNC1 in first position (GS1 format), 2 for FNC1 in second position
+	// (format given by the application indicator), or 0 if no FNC1 indicator was found.
+	Position uint8
+	// ApplicationIndicator identifies the industry format, for FNC1 in second position only.
+	ApplicationIndicator byte
+}
+
+// GetFNC1 extracts the FNC1 indicator from the header.
+// FNC1 in first position is made of the mode indicator only, whereas FNC1 in second position
+// is followed by an 8-bits application indicator.
+// The indicator is returned along with the remaining bits.
+func GetFNC1(bits []bool) (FNC1, []bool, error) {
+	mode, bits := GetMode(bits)
+	switch mode {
+	case FNC1FirstMode:
+		return FNC1{Position: 1}, bits[4:], nil
+	case FNC1SecondMode:
+		if len(bits) < 4+8 {
+			return FNC1{}, nil, errors.New("not enough bits in contents for FNC1 application indicator")
+		}
+		return FNC1{Position: 2, ApplicationIndicator: byte(BitsToUint16(bits[4:12]))}, bits[12:], nil
+	}
+	return FNC1{}, nil, fmt.Errorf("invalid FNC1 mode %s", mode)
+}
+
+// replaceFNC1Separators applies the FNC1 convention for alphanumeric text: "%" stands for the group
+// separator (GS), and "%%" stands for a literal "%".
+func replaceFNC1Separators(text string) string {
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '%' {
+			builder.WriteByte(text[i])
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '%' {
+			builder.WriteByte('%')
+			i++
+			continue
+		}
+		builder.WriteByte(GroupSeparator)
+	}
+	return builder.String()
+}
+
+// IsGS1 returns whether the given segments hold GS1 data, i.e. whether they follow an FNC1 in first position.
+func IsGS1(segments []Segment) bool {
+	return len(segments) > 0 && segments[0].FNC1.Position == 1
+}
+
+// GS1Element is a single element string of GS1 data: an Application Identifier and its value.
+type GS1Element struct {
+	// AI is the Application Identifier, e.g. "01".
+	AI string
+	// Title is the human-readable data title of the Application Identifier, e.g. "GTIN".
+	Title string
+	// Value is the element data.
+	Value string
+}
+
+// gs1AI describes the format of the data following a GS1 Application Identifier.
+type gs1AI struct {
+	title string
+	// length is the fixed data length, or the maximum data length when variable is true.
+	length   int
+	variable bool
+	// numeric tells whether data is made of digits only.
+	numeric bool
+	// checkDigit tells whether the last data digit is a GS1 check digit.
+	checkDigit bool
+	// decimal tells whether the Application Identifier has a 4th digit, giving the decimal point position.
+	decimal bool
+}
+
+// gs1AIs lists the most common GS1 Application Identifiers, by prefix.
+// Source: https://ref.gs1.org/ai/
+var gs1AIs = map[string]gs1AI{
+	"00":   {title: "SSCC", length: 18, numeric: true, checkDigit: true},
+	"01":   {title: "GTIN", length: 14, numeric: true, checkDigit: true},
+	"02":   {title: "CONTENT", length: 14, numeric: true, checkDigit: true},
+	"10":   {title: "BATCH/LOT", length: 20, variable: true},
+	"11":   {title: "PROD DATE", length: 6, numeric: true},
+	"12":   {title: "DUE DATE", length: 6, numeric: true},
+	"13":   {title: "PACK DATE", length: 6, numeric: true},
+	"15":   {title: "BEST BEFORE or BEST BY", length: 6, numeric: true},
+	"16":   {title: "SELL BY", length: 6, numeric: true},
+	"17":   {title: "USE BY or EXPIRY", length: 6, numeric: true},
+	"20":   {title: "VARIANT", length: 2, numeric: true},
+	"21":   {title: "SERIAL", length: 20, variable: true},
+	"22":   {title: "CPV", length: 20, variable: true},
+	"235":  {title: "TPX", length: 28, variable: true},
+	"240":  {title: "ADDITIONAL ID", length: 30, variable: true},
+	"241":  {title: "CUST. PART No.", length: 30, variable: true},
+	"242":  {title: "MTO VARIANT", length: 6, variable: true, numeric: true},
+	"243":  {title: "PCN", length: 20, variable: true},
+	"250":  {title: "SECONDARY SERIAL", length: 30, variable: true},
+	"251":  {title: "REF. TO SOURCE", length: 30, variable: true},
+	"253":  {title: "GDTI", length: 30, variable: true},
+	"254":  {title: "GLN EXTENSION COMPONENT", length: 20, variable: true},
+	"255":  {title: "GCN", length: 25, variable: true, numeric: true},
+	"30":   {title: "VAR. COUNT", length: 8, variable: true, numeric: true},
+	"310":  {title: "NET WEIGHT (kg)", length: 6, numeric: true, decimal: true},
+	"311":  {title: "LENGTH (m)", length: 6, numeric: true, decimal: true},
+	"312":  {title: "WIDTH (m)", length: 6, numeric: true, decimal: true},
+	"313":  {title: "HEIGHT (m)", length: 6, numeric: true, decimal: true},
+	"314":  {title: "AREA (m²)", length: 6, numeric: true, decimal: true},
+	"315":  {title: "NET VOLUME (l)", length: 6, numeric: true, decimal: true},
+	"316":  {title: "NET VOLUME (m³)", length: 6, numeric: true, decimal: true},
+	"320":  {title: "NET WEIGHT (lb)", length: 6, numeric: true, decimal: true},
+	"330":  {title: "GROSS WEIGHT (kg)", length: 6, numeric: true, decimal: true},
+	"37":   {title: "COUNT", length: 8, variable: true, numeric: true},
+	"390":  {title: "AMOUNT", length: 15, variable: true, numeric: true, decimal: true},
+	"391":  {title: "AMOUNT", length: 18, variable: true, numeric: true, decimal: true},
+	"392":  {title: "PRICE", length: 15, variable: true, numeric: true, decimal: true},
+	"393":  {title: "PRICE", length: 18, variable: true, numeric: true, decimal: true},
+	"400":  {title: "ORDER NUMBER", length: 30, variable: true},
+	"401":  {title: "GINC", length: 30, variable: true},
+	"402":  {title: "GSIN", length: 17, numeric: true, checkDigit: true},
+	"403":  {title: "ROUTE", length: 30, variable: true},
+	"410":  {title: "SHIP TO LOC", length: 13, numeric: true, checkDigit: true},
+	"411":  {title: "BILL TO", length: 13, numeric: true, checkDigit: true},
+	"412":  {title: "PURCHASE FROM", length: 13, numeric: true, checkDigit: true},
+	"413":  {title: "SHIP FOR LOC", length: 13, numeric: true, checkDigit: true},
+	"414":  {title: "LOC No.", length: 13, numeric: true, checkDigit: true},
+	"415":  {title: "PAY TO", length: 13, numeric: true, checkDigit: true},
+	"416":  {title: "PROD/SERV LOC", length: 13, numeric: true, checkDigit: true},
+	"417":  {title: "PARTY", length: 13, numeric: true, checkDigit: true},
+	"420":  {title: "SHIP TO POST", length: 20, variable: true},
+	"421":  {title: "SHIP TO POST", length: 12, variable: true},
+	"422":  {title: "ORIGIN", length: 3, numeric: true},
+	"7003": {title: "EXPIRY TIME", length: 10, numeric: true},
+	"8003": {title: "GRAI", length: 30, variable: true},
+	"8004": {title: "GIAI", length: 30, variable: true},
+	"8006": {title: "ITIP", length: 18, numeric: true},
+	"8017": {title: "GSRN - PROVIDER", length: 18, numeric: true, checkDigit: true},
+	"8018": {title: "GSRN - RECIPIENT", length: 18, numeric: true, checkDigit: true},
+	"8020": {title: "REF No.", length: 25, variable: true},
+	"8200": {title: "PRODUCT URL", length: 70, variable: true},
+	"90":   {title: "INTERNAL", length: 30, variable: true},
+	"91":   {title: "INTERNAL", length: 90, variable: true},
+	"92":   {title: "INTERNAL", length: 90, variable: true},
+	"93":   {title: "INTERNAL", length: 90, variable: true},
+	"94":   {title: "INTERNAL", length: 90, variable: true},
+	"95":   {title: "INTERNAL", length: 90, variable: true},
+	"96":   {title: "INTERNAL", length: 90, variable: true},
+	"97":   {title: "INTERNAL", length: 90, variable: true},
+	"98":   {title: "INTERNAL", length: 90, variable: true},
+	"99":   {title: "INTERNAL", length: 90, variable: true},
+}
+
+// ParseGS1 splits GS1 data into its element strings: each one starts with an Application Identifier,
+// followed by a fixed-length value, or a variable-length value terminated by a group separator (or the end of data).
+// Check digits are validated, when relevant.
+func ParseGS1(data string) ([]GS1Element, error) {
+	elements := make([]GS1Element, 0, 4)
+
+	for len(data) > 0 {
+		ai, format, err := findGS1AI(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[len(ai):]
+
+		var value string
+		if format.variable {
+			end := strings.IndexRune(data, GroupSeparator)
+			if end < 0 {
+				end = len(data)
+			}
+			value, data = data[:end], data[end:]
+			if len(value) > format.length {
+				return nil, fmt.Errorf("AI (%s): value too long, expected at most %d characters but got %d", ai, format.length, len(value))
+			}
+		} else {
+			if len(data) < format.length {
+				return nil, fmt.Errorf("AI (%s): value too short, expected %d characters but got %d", ai, format.length, len(data))
+			}
+			value, data = data[:format.length], data[format.length:]
+		}
+		data = strings.TrimPrefix(data, string(GroupSeparator)) // also allowed after fixed-length values
+
+		if format.numeric && strings.Trim(value, "0123456789") != "" {
+			return nil, fmt.Errorf("AI (%s): value %q is not numeric", ai, value)
+		}
+		if format.checkDigit && !validGS1CheckDigit(value) {
+			return nil, fmt.Errorf("AI (%s): invalid check digit in %q", ai, value)
+		}
+
+		elements = append(elements, GS1Element{AI: ai, Title: format.title, Value: value})
+	}
+
+	return elements, nil
+}
+
+// findGS1AI returns the Application Identifier found at the beginning of the data, along with its format.
+func findGS1AI(data string) (string, gs1AI, error) {
+	for length := 2; length <= 4 && length <= len(data); length++ {
+		format, ok := gs1AIs[data[:length]]
+		if !ok {
+			continue
+		}
+		if format.decimal {
+			length++ // 4th digit is the decimal point position
+			if length > len(data) || data[length-1] < '0' || data[length-1] > '9' {
+				return "", gs1AI{}, fmt.Errorf("AI (%s): missing decimal point position", data[:length-1])
+			}
+		}
+		return data[:length], format, nil
+	}
+	return "", gs1AI{}, fmt.Errorf("unknown GS1 Application Identifier at %q", data)
+}
+
+// validGS1CheckDigit returns whether the last digit of the given numeric value is a valid GS1 check digit:
+// digits are weighted 3 and 1 alternately, starting from the right, and the check digit brings the sum
+// to a multiple of 10.
+// See https://www.gs1.org/services/how-calculate-check-digit-manually
+func validGS1CheckDigit(value string) bool {
+	sum := 0
+	for i := len(value) - 2; i >= 0; i-- {
+		digit := int(value[i] - '0')
+		if (len(value)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return int(value[len(value)-1]-'0') == (10-sum%10)%10
+}
@@ -0,0 +1,126 @@
+package decode
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetFNC1(t *testing.T) {
+	type test struct {
+		name              string
+		bits              string
+		expectedFNC1      FNC1
+		expectedRemaining int
+		expectedError     bool
+	}
+	tests := []test{
+		{
+			name:              "first position",
+			bits:              "0101" + "0010",
+			expectedFNC1:      FNC1{Position: 1},
+			expectedRemaining: 4,
+		},
+		{
+			name:              "second position",
+			bits:              "1001" + "01100101" + "0010",
+			expectedFNC1:      FNC1{Position: 2, ApplicationIndicator: 0b01100101},
+			expectedRemaining: 4,
+		},
+		{
+			name:          "missing application indicator",
+			bits:          "1001" + "0110",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualFNC1, remaining, err := GetFNC1(bitsFromString(test.bits))
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got %+v", actualFNC1)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if actualFNC1 != test.expectedFNC1 {
+				t.Errorf("expected %+v but got %+v", test.expectedFNC1, actualFNC1)
+			}
+			if len(remaining) != test.expectedRemaining {
+				t.Errorf("expected %d remaining bits but got %d", test.expectedRemaining, len(remaining))
+			}
+		})
+	}
+}
+
+func TestParseGS1(t *testing.T) {
+	type test struct {
+		name             string
+		data             string
+		expectedElements []GS1Element
+		expectedError    bool
+	}
+	tests := []test{
+		{
+			name: "fixed and variable length elements",
+			data: "0109506000134352" + "17201225" + "10ABC123\x1d" + "3103000750" + "21XYZ",
+			expectedElements: []GS1Element{
+				{AI: "01", Title: "GTIN", Value: "09506000134352"},
+				{AI: "17", Title: "USE BY or EXPIRY", Value: "201225"},
+				{AI: "10", Title: "BATCH/LOT", Value: "ABC123"},
+				{AI: "3103", Title: "NET WEIGHT (kg)", Value: "000750"},
+				{AI: "21", Title: "SERIAL", Value: "XYZ"},
+			},
+		},
+		{
+			name: "separator after fixed-length element",
+			data: "0109506000134352\x1d" + "21XYZ",
+			expectedElements: []GS1Element{
+				{AI: "01", Title: "GTIN", Value: "09506000134352"},
+				{AI: "21", Title: "SERIAL", Value: "XYZ"},
+			},
+		},
+		{
+			name:          "invalid check digit",
+			data:          "0109506000134353",
+			expectedError: true,
+		},
+		{
+			name:          "value too short",
+			data:          "01095060001343",
+			expectedError: true,
+		},
+		{
+			name:          "variable value too long",
+			data:          "21" + "123456789012345678901",
+			expectedError: true,
+		},
+		{
+			name:          "unknown application identifier",
+			data:          "0409506000134352",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualElements, err := ParseGS1(test.data)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got %v", actualElements)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if !slices.Equal(actualElements, test.expectedElements) {
+				t.Errorf("expected %v but got %v", test.expectedElements, actualElements)
+			}
+		})
+	}
+}
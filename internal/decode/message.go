@@ -2,17 +2,25 @@ package decode
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 )
 
 // Message decodes the given binary contents, of the given length and for the given mode, to text.
-// Kanji mode is currently not implemented.
+// Kanji characters are converted from Shift JIS to UTF-8.
 func Message(mode Mode, length uint, contents []bool) (string, error) {
+	raw, err := messageBytes(mode, length, contents)
+	if err != nil {
+		return "", err
+	}
 	if mode == KanjiMode {
-		return "", errors.New("Kanji mode is not supported")
+		return decodeCharset(string(raw), shiftJISCharset)
 	}
+	return string(raw), nil
+}
 
+// messageBytes decodes the given binary contents, of the given length and for the given mode, to raw bytes
+// (ASCII characters for numeric and alphanumeric modes, Shift JIS characters for Kanji mode).
+func messageBytes(mode Mode, length uint, contents []bool) ([]byte, error) {
 	var blockSize, charactersPerBlock uint
 	switch mode {
 	case NumericMode:
@@ -21,9 +29,11 @@ func Message(mode Mode, length uint, contents []bool) (string, error) {
 		blockSize, charactersPerBlock = 11, 2 // 2 alpha-numeric characters (0-9 + uppercase letters + 9 symbols) on 10 bits
 	case ByteMode:
 		blockSize, charactersPerBlock = 8, 1 // 1 ASCII character on 8 bits
+	case KanjiMode:
+		blockSize, charactersPerBlock = 13, 1 // 1 double-byte Shift JIS character on 13 bits
 	}
 	if len(contents)*int(charactersPerBlock) < int(length*blockSize) {
-		return "", fmt.Errorf("missing data in contents, not enough bits to encode the expected %d characters", length)
+		return nil, fmt.Errorf("missing data in contents, not enough bits to encode the expected %d characters", length)
 	}
 
 	var buffer bytes.Buffer
@@ -45,7 +55,7 @@ func Message(mode Mode, length uint, contents []bool) (string, error) {
 		i += int(blockSize)
 	}
 
-	return buffer.String(), nil
+	return buffer.Bytes(), nil
 }
 
 const alphanumericCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
@@ -63,7 +73,21 @@ func decodeCharacters(mode Mode, charactersBits []bool, nbCharacters uint) []byt
 		return []byte{alphanumericCharacters[val/45], alphanumericCharacters[val%45]}
 	} else if mode == ByteMode {
 		return []byte{byte(val)}
+	} else if mode == KanjiMode {
+		return kanjiToShiftJIS(val)
 	}
 
 	return nil
 }
+
+// kanjiToShiftJIS converts a 13-bits Kanji value back to its double-byte Shift JIS representation.
+// See https://www.thonky.com/qr-code-tutorial/kanji-mode-encoding
+func kanjiToShiftJIS(val uint16) []byte {
+	n := (val/0xC0)<<8 | val%0xC0 // most significant byte was multiplied by 0xC0
+	if n < 0x1F00 {
+		n += 0x8140 // characters from 0x8140 to 0x9FFC
+	} else {
+		n += 0xC140 // characters from 0xE040 to 0xEBBF
+	}
+	return []byte{byte(n >> 8), byte(n)}
+}
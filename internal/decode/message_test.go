@@ -175,3 +175,36 @@ func TestMessage_Byte(t *testing.T) {
 		})
 	}
 }
+
+func TestMessage_Kanji(t *testing.T) {
+	type test struct {
+		name            string
+		length          uint
+		contents        []bool
+		expectedMessage string
+	}
+	tests := []test{
+		{
+			name:   "characters from both Shift JIS ranges",
+			length: 2,
+			contents: []bool{
+				_1, _1, _0, _1, _0, _1, _0, _1, _0, _1, _0, _1, _0, // 0xE4AA
+				_0, _0, _1, _1, _0, _1, _0, _0, _1, _0, _1, _1, _1, // 0x89D7
+			},
+			expectedMessage: "茗荷",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualMessage, err := Message(KanjiMode, test.length, test.contents)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if actualMessage != test.expectedMessage {
+				t.Errorf("expected %q but got %q", test.expectedMessage, actualMessage)
+			}
+		})
+	}
+}
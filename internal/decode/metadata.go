@@ -8,10 +8,14 @@ import (
 type Mode uint8
 
 const (
-	NumericMode      Mode = 0b0001
-	AlphanumericMode Mode = 0b0010
-	ByteMode         Mode = 0b0100
-	KanjiMode        Mode = 0b1000
+	NumericMode          Mode = 0b0001
+	AlphanumericMode     Mode = 0b0010
+	ByteMode             Mode = 0b0100
+	KanjiMode            Mode = 0b1000
+	ECIMode              Mode = 0b0111
+	StructuredAppendMode Mode = 0b0011
+	FNC1FirstMode        Mode = 0b0101
+	FNC1SecondMode       Mode = 0b1001
 )
 
 func (m Mode) String() string {
@@ -24,22 +28,24 @@ func (m Mode) String() string {
 		return "Byte"
 	case KanjiMode:
 		return "Kanji"
+	case ECIMode:
+		return "ECI"
+	case StructuredAppendMode:
+		return "StructuredAppend"
+	case FNC1FirstMode:
+		return "FNC1(1st)"
+	case FNC1SecondMode:
+		return "FNC1(2nd)"
 	}
 	return fmt.Sprintf("Unknown(%b)", m)
 }
 
 // GetMode extracts the contents type from the header.
-// The first 4 bits are used.
-// In case ECI character is found, ECI data is ignored, and the trimmed bits are returned.
+// The first 4 bits are used, and the bits are returned untouched.
+// In case ECI mode is found, the ECI designator should then be read with GetECI.
 // See https://www.thonky.com/qr-code-tutorial/data-encoding#step-3-add-the-mode-indicator
 func GetMode(bits []bool) (Mode, []bool) {
 	mode := BitsToUint16(bits[:4])
-	if mode == 0b0111 {
-		// ECI escape character found, it can be used for unicode encoding.
-		// Skip character (no unicode support yet) and next byte, then start over reading mode.
-		bits = bits[12:]
-		mode = BitsToUint16(bits[:4])
-	}
 	return Mode(mode), bits
 }
 
@@ -49,7 +55,7 @@ func GetMode(bits []bool) (Mode, []bool) {
 // Also, the contents bits are returned after trimming the header.
 // See https://www.thonky.com/qr-code-tutorial/data-encoding#step-4-add-the-character-count-indicator
 func GetContentLength(bits []bool, version uint, mode Mode, errorCorrectionLevel ErrorCorrectionLevel) (uint, []bool, error) {
-	nb := lengthBytes(version, mode)
+	nb := LengthBits(version, mode)
 	if nb == 0 {
 		return 0, nil, fmt.Errorf("invalid version-mode (%d, %b) pair", version, mode)
 	}
@@ -58,14 +64,16 @@ func GetContentLength(bits []bool, version uint, mode Mode, errorCorrectionLevel
 	}
 
 	length := uint(BitsToUint16(bits[4 : 4+nb]))
-	if length <= 0 || length > capacity(version, errorCorrectionLevel, mode) {
+	if length <= 0 || length > Capacity(version, errorCorrectionLevel, mode) {
 		return 0, nil, fmt.Errorf("invalid length %d", length)
 	}
 
 	return length, bits[4+nb:], nil
 }
 
-func lengthBytes(version uint, mode Mode) int {
+// LengthBits returns the number of bits used to encode the contents length (character count indicator)
+// for the given version and mode, or 0 if the mode has no length.
+func LengthBits(version uint, mode Mode) int {
 	if version <= 9 {
 		switch mode {
 		case NumericMode:
@@ -114,7 +122,9 @@ func lengthBytes(version uint, mode Mode) int {
 	return 0
 }
 
-func capacity(version uint, errorCorrectionLevel ErrorCorrectionLevel, mode Mode) uint {
+// Capacity returns the maximum number of characters a QR-code of the given version and error correction level
+// may hold, when encoded with the given mode only.
+func Capacity(version uint, errorCorrectionLevel ErrorCorrectionLevel, mode Mode) uint {
 	if capacityByErrorCorrectionLevelByMode, ok := capacityByVersionByErrorCorrectionLevelByMode[version]; ok {
 		if capacityByMode, ok := capacityByErrorCorrectionLevelByMode[errorCorrectionLevel]; ok {
 			if capacity, ok := capacityByMode[mode]; ok {
@@ -0,0 +1,113 @@
+package decode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// terminatorMode is the mode indicator marking the end of the data, when there is enough room left for it.
+const terminatorMode Mode = 0b0000
+
+// Segment is a part of the message, whose characters are all encoded with the same mode.
+type Segment struct {
+	Mode Mode
+	// Length is the number of characters in the segment.
+	Length uint
+	// Text is the decoded segment contents.
+	Text string
+	// Bytes is the raw segment contents, before conversion to text.
+	Bytes []byte
+	// Charset is the name of the character set used to interpret the segment contents, for Byte mode only.
+	Charset string
+	// FNC1 tells whether the segment contents follow a specific industry format.
+	FNC1 FNC1
+}
+
+// Segments decodes all the segments found in the given (corrected) contents bits, until the terminator is found,
+// or until all the bits are consumed. Each segment starts with its own mode indicator and character count.
+// Byte mode segments are interpreted with the character set given by the last ECI designator found,
+// or ISO-8859-1 by default. After an FNC1 indicator, the "%" character of Alphanumeric mode segments
+// is interpreted as a group separator (GS), and "%%" as a single "%".
+// The full message (concatenation of all segments texts) is returned, along with the list of segments.
+// See https://www.thonky.com/qr-code-tutorial/data-encoding#mixing-modes
+func Segments(bits []bool, version uint, errorCorrectionLevel ErrorCorrectionLevel) (string, []Segment, error) {
+	var message strings.Builder
+	segments := make([]Segment, 0, 1)
+	charset := DefaultCharset
+	var fnc1 FNC1
+
+	for len(bits) >= 4 {
+		mode, modeBits := GetMode(bits)
+		if mode == terminatorMode {
+			break
+		}
+		switch mode {
+		case ECIMode:
+			var err error
+			_, charset, bits, err = GetECI(modeBits)
+			if err != nil {
+				return "", nil, err
+			}
+			continue
+		case FNC1FirstMode, FNC1SecondMode:
+			var err error
+			fnc1, bits, err = GetFNC1(modeBits)
+			if err != nil {
+				return "", nil, err
+			}
+			continue
+		}
+
+		length, contents, err := GetContentLength(modeBits, version, mode, errorCorrectionLevel)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid segment %d: %w", len(segments)+1, err)
+		}
+
+		raw, err := messageBytes(mode, length, contents)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid segment %d: %w", len(segments)+1, err)
+		}
+
+		segment := Segment{Mode: mode, Length: length, Text: string(raw), Bytes: raw, FNC1: fnc1}
+		switch mode {
+		case AlphanumericMode:
+			if fnc1.Position != 0 {
+				segment.Text = replaceFNC1Separators(segment.Text)
+			}
+		case ByteMode:
+			segment.Charset = charset.Name
+			segment.Text, err = decodeCharset(string(raw), charset)
+		case KanjiMode:
+			segment.Text, err = decodeCharset(string(raw), shiftJISCharset)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid segment %d: %w", len(segments)+1, err)
+		}
+
+		segments = append(segments, segment)
+		message.WriteString(segment.Text)
+		bits = contents[contentBitsLength(mode, length):]
+	}
+
+	if len(segments) == 0 {
+		return "", nil, errors.New("no segment found in contents")
+	}
+	return message.String(), segments, nil
+}
+
+// contentBitsLength returns the number of bits used to encode length characters in the given mode.
+func contentBitsLength(mode Mode, length uint) int {
+	n := int(length)
+	switch mode {
+	case NumericMode:
+		return 10*(n/3) + []int{0, 4, 7}[n%3] // 3 characters on 10 bits, then 1 on 4 bits or 2 on 7 bits
+	case AlphanumericMode:
+		return 11*(n/2) + 6*(n%2) // 2 characters on 11 bits, then 1 on 6 bits
+	case ByteMode:
+		return 8 * n
+	case KanjiMode:
+		return 13 * n
+	}
+	return 0
+}
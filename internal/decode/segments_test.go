@@ -0,0 +1,144 @@
+package decode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegments(t *testing.T) {
+	type test struct {
+		name             string
+		bits             string
+		expectedMessage  string
+		expectedSegments []Segment
+		expectedError    bool
+	}
+	tests := []test{
+		{
+			name: "single segment",
+			bits: "0001" + "0000000011" + "0001111011" + // Numeric "123"
+				"0000" + "0000", // terminator + padding
+			expectedMessage: "123",
+			expectedSegments: []Segment{
+				{Mode: NumericMode, Length: 3, Text: "123", Bytes: []byte("123")},
+			},
+		},
+		{
+			name: "numeric, byte and alphanumeric segments",
+			bits: "0001" + "0000000011" + "0001111011" + // Numeric "123"
+				"0100" + "00000010" + "01100001" + "01100010" + // Byte "ab"
+				"0010" + "000000010" + "01000011111" + // Alphanumeric "C3"
+				"0000" + "00" + "11101100", // terminator + padding
+			expectedMessage: "123abC3",
+			expectedSegments: []Segment{
+				{Mode: NumericMode, Length: 3, Text: "123", Bytes: []byte("123")},
+				{Mode: ByteMode, Length: 2, Text: "ab", Bytes: []byte("ab"), Charset: "ISO-8859-1"},
+				{Mode: AlphanumericMode, Length: 2, Text: "C3", Bytes: []byte("C3")},
+			},
+		},
+		{
+			name: "no room left for terminator",
+			bits: "0100" + "00000001" + "01100001" + // Byte "a"
+				"0001" + "0000000001" + "0011", // Numeric "3"
+			expectedMessage: "a3",
+			expectedSegments: []Segment{
+				{Mode: ByteMode, Length: 1, Text: "a", Bytes: []byte("a"), Charset: "ISO-8859-1"},
+				{Mode: NumericMode, Length: 1, Text: "3", Bytes: []byte("3")},
+			},
+		},
+		{
+			name: "default ISO-8859-1 character set",
+			bits: "0100" + "00000010" + "01100011" + "11101001" + // Byte "cé"
+				"0000",
+			expectedMessage: "cé",
+			expectedSegments: []Segment{
+				{Mode: ByteMode, Length: 2, Text: "cé", Bytes: []byte{0x63, 0xE9}, Charset: "ISO-8859-1"},
+			},
+		},
+		{
+			name: "ECI applied to subsequent byte segments",
+			bits: "0111" + "00011010" + // ECI 26 (UTF-8)
+				"0100" + "00000011" + "01100011" + "11000011" + "10101001" + // Byte "cé"
+				"0001" + "0000000001" + "0011" + // Numeric "3"
+				"0100" + "00000010" + "11000011" + "10101000" + // Byte "è"
+				"0000",
+			expectedMessage: "cé3è",
+			expectedSegments: []Segment{
+				{Mode: ByteMode, Length: 3, Text: "cé", Bytes: []byte("cé"), Charset: "UTF-8"},
+				{Mode: NumericMode, Length: 1, Text: "3", Bytes: []byte("3")},
+				{Mode: ByteMode, Length: 2, Text: "è", Bytes: []byte("è"), Charset: "UTF-8"},
+			},
+		},
+		{
+			name: "ECI change between segments",
+			bits: "0111" + "00000111" + // ECI 7 (ISO-8859-5)
+				"0100" + "00000001" + "11100100" + // Byte "ф"
+				"0111" + "00010100" + // ECI 20 (Shift JIS)
+				"0100" + "00000010" + "10000010" + "10100000" + // Byte "あ"
+				"0000",
+			expectedMessage: "фあ",
+			expectedSegments: []Segment{
+				{Mode: ByteMode, Length: 1, Text: "ф", Bytes: []byte{0xE4}, Charset: "ISO-8859-5"},
+				{Mode: ByteMode, Length: 2, Text: "あ", Bytes: []byte{0x82, 0xA0}, Charset: "Shift_JIS"},
+			},
+		},
+		{
+			name: "FNC1 in first position with alphanumeric separators",
+			bits: "0101" + // FNC1 in first position
+				"0010" + "000001000" + "00000101101" + "00111001101" + "11010110000" + "00000111001" + // Alphanumeric "10AB%21C"
+				"0000",
+			expectedMessage: "10AB\x1d21C",
+			expectedSegments: []Segment{
+				{Mode: AlphanumericMode, Length: 8, Text: "10AB\x1d21C", Bytes: []byte("10AB%21C"), FNC1: FNC1{Position: 1}},
+			},
+		},
+		{
+			name: "unsupported ECI",
+			bits: "0111" + "00001110" + // ECI 14 (unassigned)
+				"0100" + "00000001" + "01100001" + // Byte "a"
+				"0000",
+			expectedError: true,
+		},
+		{
+			name:          "truncated segment",
+			bits:          "0100" + "00000010" + "01100001" + "0110", // Byte "ab", missing bits
+			expectedError: true,
+		},
+		{
+			name:          "empty contents",
+			bits:          "0000" + "0000",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualMessage, actualSegments, err := Segments(bitsFromString(test.bits), 1, ErrorCorrectionLevelLow)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got message %q", actualMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if actualMessage != test.expectedMessage {
+				t.Errorf("expected %q but got %q", test.expectedMessage, actualMessage)
+			}
+			if !reflect.DeepEqual(actualSegments, test.expectedSegments) {
+				t.Errorf("expected segments %v but got %v", test.expectedSegments, actualSegments)
+			}
+		})
+	}
+}
+
+// bitsFromString converts a string of 0's and 1's to the corresponding sequence of bits.
+func bitsFromString(s string) []bool {
+	bits := make([]bool, 0, len(s))
+	for _, c := range s {
+		bits = append(bits, c == '1')
+	}
+	return bits
+}
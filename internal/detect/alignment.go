@@ -0,0 +1,106 @@
+package detect
+
+import (
+	"math"
+)
+
+// Inspired from https://www.thonky.com/qr-code-tutorial/alignment-pattern-locations
+
+// alignmentPatternPositions lists, for each version, the row and column coordinates of the alignment patterns centers.
+// Alignment patterns are placed at all combinations of these coordinates, except those overlapping
+// the 3 finder markers. Version 1 has no alignment pattern.
+var alignmentPatternPositions = [41][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+	11: {6, 30, 54},
+	12: {6, 32, 58},
+	13: {6, 34, 62},
+	14: {6, 26, 46, 66},
+	15: {6, 26, 48, 70},
+	16: {6, 26, 50, 74},
+	17: {6, 30, 54, 78},
+	18: {6, 30, 56, 82},
+	19: {6, 30, 58, 86},
+	20: {6, 34, 62, 90},
+	21: {6, 28, 50, 72, 94},
+	22: {6, 26, 50, 74, 98},
+	23: {6, 30, 54, 78, 102},
+	24: {6, 28, 54, 80, 106},
+	25: {6, 32, 58, 84, 110},
+	26: {6, 30, 58, 86, 114},
+	27: {6, 34, 62, 90, 118},
+	28: {6, 26, 50, 74, 98, 122},
+	29: {6, 30, 54, 78, 102, 126},
+	30: {6, 26, 52, 78, 104, 130},
+	31: {6, 30, 56, 82, 108, 134},
+	32: {6, 34, 60, 86, 112, 138},
+	33: {6, 30, 58, 86, 114, 142},
+	34: {6, 34, 62, 90, 118, 146},
+	35: {6, 30, 54, 78, 102, 126, 150},
+	36: {6, 24, 50, 76, 102, 128, 154},
+	37: {6, 28, 54, 80, 106, 132, 158},
+	38: {6, 32, 58, 84, 110, 136, 162},
+	39: {6, 26, 54, 82, 110, 138, 166},
+	40: {6, 30, 58, 86, 114, 142, 170},
+}
+
+// AlignmentPatternPositions returns the row and column coordinates of the alignment patterns centers
+// for the given version, or nil if there is none.
+func AlignmentPatternPositions(version uint) []int {
+	if version >= uint(len(alignmentPatternPositions)) {
+		return nil
+	}
+	return alignmentPatternPositions[version]
+}
+
+// Inspired from ZXing's AlignmentPatternFinder:
+// https://github.com/zxing/zxing/blob/master/core/src/main/java/com/google/zxing/qrcode/detector/AlignmentPatternFinder.java
+
+// findAlignmentPattern looks for an alignment pattern around the estimated position (x, y), within
+// the given radius (in pixels). Across its center, an alignment pattern shows black, white, black, white and black
+// runs of the same length (1:1:1:1:1 ratio), starting and ending with its outer ring.
+// It returns the center of the pattern closest to the estimated position.
+func findAlignmentPattern(b *bitmap, x, y, moduleSize, radius float64) (float64, float64, bool) {
+	bestX, bestY, bestDistance := 0., 0., math.Inf(1)
+
+	for row := int(y - radius); row <= int(y+radius); row++ {
+		for col := int(x - radius); col <= int(x+radius); col++ {
+			if !b.at(col, row) || b.at(col-1, row) {
+				continue // only check from the beginning of black runs
+			}
+			horizontalCounts, centerX, ok := runsAcross(b, col, row, 1, 0, int(4*moduleSize))
+			if !ok || !isAlignmentRatio(horizontalCounts, moduleSize) {
+				continue
+			}
+			verticalCounts, centerY, ok := runsAcross(b, int(centerX), row, 0, 1, int(4*moduleSize))
+			if !ok || !isAlignmentRatio(verticalCounts, moduleSize) {
+				continue
+			}
+
+			if d := math.Hypot(centerX-x, centerY-y); d < bestDistance {
+				bestX, bestY, bestDistance = centerX, centerY, d
+			}
+		}
+	}
+
+	return bestX, bestY, !math.IsInf(bestDistance, 1)
+}
+
+// isAlignmentRatio returns whether the runs lengths are close to the given module size.
+// Outer black runs may be longer, when the dots next to the pattern are black too.
+func isAlignmentRatio(counts [5]int, moduleSize float64) bool {
+	variance := moduleSize/2 + 1
+	for _, count := range counts[1:4] {
+		if math.Abs(float64(count)-moduleSize) > variance {
+			return false
+		}
+	}
+	return float64(counts[0]) > moduleSize-variance && float64(counts[4]) > moduleSize-variance
+}
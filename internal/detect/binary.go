@@ -0,0 +1,302 @@
+package detect
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// grayImage is the luminance of an image, one byte per pixel.
+type grayImage struct {
+	width, height int
+	pix           []uint8
+}
+
+// newGrayImage computes the luminance of each pixel of the given image.
+func newGrayImage(img image.Image) *grayImage {
+	bounds := img.Bounds()
+	gray := &grayImage{
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		pix:    make([]uint8, bounds.Dx()*bounds.Dy()),
+	}
+
+	switch img := img.(type) {
+	case *image.Gray:
+		for y := range gray.height {
+			copy(gray.pix[y*gray.width:(y+1)*gray.width], img.Pix[y*img.Stride:])
+		}
+	case *image.YCbCr:
+		// JPEG images: luminance is stored as is
+		for y := range gray.height {
+			copy(gray.pix[y*gray.width:(y+1)*gray.width], img.Y[y*img.YStride:])
+		}
+	default:
+		for y := range gray.height {
+			for x := range gray.width {
+				r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				gray.pix[y*gray.width+x] = uint8((299*r + 587*g + 114*b) / 1000 >> 8)
+			}
+		}
+	}
+	return gray
+}
+
+// at returns the luminance of the pixel at position (x, y). Pixels out of the image are white.
+func (g *grayImage) at(x, y int) float64 {
+	if x < 0 || y < 0 || x >= g.width || y >= g.height {
+		return 255
+	}
+	return float64(g.pix[y*g.width+x])
+}
+
+// negative returns the image with its luminance reversed: light-on-dark QR-codes become dark-on-light.
+func (g *grayImage) negative() *grayImage {
+	negative := &grayImage{width: g.width, height: g.height, pix: make([]uint8, len(g.pix))}
+	for i, luminance := range g.pix {
+		negative.pix[i] = 255 - luminance
+	}
+	return negative
+}
+
+// bitmap is a binarized image, "true" meaning black pixel.
+type bitmap struct {
+	width, height int
+	black         []bool
+	// thresholds are the luminance thresholds each pixel was compared with, or nil if all pixels were compared
+	// with threshold
+	thresholds []float32
+	threshold  float64
+}
+
+// at returns whether the pixel at position (x, y) is black. Pixels out of the image are white.
+func (b *bitmap) at(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return false
+	}
+	return b.black[y*b.width+x]
+}
+
+// thresholdAt returns the luminance threshold the pixel at position (x, y) was compared with.
+// Pixels out of the image get the threshold of the closest pixel.
+func (b *bitmap) thresholdAt(x, y int) float64 {
+	if b.thresholds == nil {
+		return b.threshold
+	}
+	x, y = min(max(x, 0), b.width-1), min(max(y, 0), b.height-1)
+	return float64(b.thresholds[y*b.width+x])
+}
+
+// Binarization is the method used to tell black pixels from white ones.
+type Binarization uint8
+
+const (
+	// BinarizationOtsu uses a single threshold for the whole image, which best separates its luminance histogram
+	// into two classes (Otsu's method). Once the QR-code is located, the threshold is refined from its finder patterns.
+	BinarizationOtsu Binarization = iota
+	// BinarizationMean compares each pixel with the mean luminance of its neighborhood, to resist uneven lighting.
+	BinarizationMean
+	// BinarizationSauvola compares each pixel with a threshold computed from the mean and the standard deviation
+	// of its neighborhood (Sauvola's method), to resist uneven lighting and low contrast.
+	BinarizationSauvola
+)
+
+func (m Binarization) String() string {
+	switch m {
+	case BinarizationOtsu:
+		return "otsu"
+	case BinarizationMean:
+		return "mean"
+	case BinarizationSauvola:
+		return "sauvola"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler, so that the method may be given as a command-line flag.
+func (m Binarization) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, so that the method may be given as a command-line flag.
+func (m *Binarization) UnmarshalText(text []byte) error {
+	for _, method := range []Binarization{BinarizationOtsu, BinarizationMean, BinarizationSauvola} {
+		if string(text) == method.String() {
+			*m = method
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown binarization method %q, expected otsu, mean or sauvola", text)
+}
+
+const (
+	// meanOffset is subtracted from the neighborhood mean luminance, so that uniform areas are white.
+	meanOffset = 8
+	// sauvolaK weights the influence of the standard deviation in Sauvola's method.
+	sauvolaK = 0.2
+	// sauvolaR is the dynamic range of the standard deviation, in Sauvola's method.
+	sauvolaR = 128
+)
+
+// binarize converts the luminance image into a black and white image, with the given method.
+func binarize(gray *grayImage, method Binarization) *bitmap {
+	switch method {
+	case BinarizationMean, BinarizationSauvola:
+		return binarizeLocal(gray, method)
+	}
+	return binarizeGlobal(gray, otsuThreshold(gray))
+}
+
+// binarizeGlobal converts the luminance image into a black and white image: pixels darker than the threshold are black.
+func binarizeGlobal(gray *grayImage, threshold int) *bitmap {
+	b := &bitmap{width: gray.width, height: gray.height, black: make([]bool, len(gray.pix)), threshold: float64(threshold)}
+	for i, luminance := range gray.pix {
+		b.black[i] = int(luminance) < threshold
+	}
+	return b
+}
+
+// otsuThreshold returns the luminance threshold which maximizes the variance between the dark pixels (below)
+// and the light pixels (above or equal).
+// See https://en.wikipedia.org/wiki/Otsu%27s_method
+func otsuThreshold(gray *grayImage) int {
+	var histogram [256]int
+	for _, luminance := range gray.pix {
+		histogram[luminance]++
+	}
+
+	total, sum := 0., 0.
+	for luminance, count := range histogram {
+		total += float64(count)
+		sum += float64(luminance * count)
+	}
+
+	threshold := 128
+	bestVariance := -1.
+	darkCount, darkSum := 0., 0.
+	for luminance, count := range histogram {
+		darkCount += float64(count)
+		darkSum += float64(luminance * count)
+		lightCount := total - darkCount
+		if darkCount == 0 {
+			continue
+		}
+		if lightCount == 0 {
+			break
+		}
+		darkMean, lightMean := darkSum/darkCount, (sum-darkSum)/lightCount
+		if variance := darkCount * lightCount * (darkMean - lightMean) * (darkMean - lightMean); variance > bestVariance {
+			threshold, bestVariance = luminance+1, variance
+		}
+	}
+	return threshold
+}
+
+// binarizeLocal converts the luminance image into a black and white image, with a threshold computed for each pixel
+// from its neighborhood: a square window large enough to hold several dots, even for small codes filling the image.
+// Sums over the windows are computed in constant time from the integral images of the luminance and its square.
+func binarizeLocal(gray *grayImage, method Binarization) *bitmap {
+	radius := max(7, max(gray.width, gray.height)/12)
+
+	stride := gray.width + 1
+	sums := make([]int64, stride*(gray.height+1))
+	squares := make([]int64, stride*(gray.height+1))
+	for y := range gray.height {
+		var lineSum, lineSquare int64
+		for x := range gray.width {
+			luminance := int64(gray.pix[y*gray.width+x])
+			lineSum += luminance
+			lineSquare += luminance * luminance
+			sums[(y+1)*stride+x+1] = sums[y*stride+x+1] + lineSum
+			squares[(y+1)*stride+x+1] = squares[y*stride+x+1] + lineSquare
+		}
+	}
+	windowSum := func(integral []int64, x0, y0, x1, y1 int) float64 {
+		return float64(integral[y1*stride+x1] - integral[y0*stride+x1] - integral[y1*stride+x0] + integral[y0*stride+x0])
+	}
+
+	b := &bitmap{width: gray.width, height: gray.height, black: make([]bool, len(gray.pix)), thresholds: make([]float32, len(gray.pix))}
+	for y := range gray.height {
+		y0, y1 := max(0, y-radius), min(gray.height, y+radius+1)
+		for x := range gray.width {
+			x0, x1 := max(0, x-radius), min(gray.width, x+radius+1)
+			count := float64((x1 - x0) * (y1 - y0))
+			mean := windowSum(sums, x0, y0, x1, y1) / count
+
+			var threshold float64
+			if method == BinarizationSauvola {
+				deviation := math.Sqrt(max(0, windowSum(squares, x0, y0, x1, y1)/count-mean*mean))
+				threshold = mean * (1 + sauvolaK*(deviation/sauvolaR-1))
+			} else {
+				threshold = mean - meanOffset
+			}
+			b.black[y*gray.width+x] = float64(gray.pix[y*gray.width+x]) < threshold
+			b.thresholds[y*gray.width+x] = float32(threshold)
+		}
+	}
+	return b
+}
+
+// finderThreshold returns the luminance threshold halfway between the known-dark and known-light dots of the
+// three finder patterns of a QR-code of the given size, located in the image with the given transformation
+// from dots coordinates to image coordinates.
+// It fails if the finder patterns are not contrasted enough.
+func finderThreshold(gray *grayImage, transform homography, size int) (int, bool) {
+	darkMean, lightMean, ok := finderLevels(gray, transform, size)
+	if !ok || lightMean-darkMean < minFinderContrast {
+		return 0, false
+	}
+	return (darkMean + lightMean + 1) / 2, true
+}
+
+// finderLevels returns the mean luminance of the known-dark and known-light dots of the three finder patterns
+// of a QR-code of the given size, located in the image with the given transformation from dots coordinates
+// to image coordinates. It fails if the finder patterns are out of the image.
+func finderLevels(gray *grayImage, transform homography, size int) (int, int, bool) {
+	var dark, light []int
+	for _, origin := range [3][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for i := range 7 {
+			for j := range 7 {
+				x, y := transform.apply(float64(origin[0]+j)+0.5, float64(origin[1]+i)+0.5)
+				xi, yi := int(math.Floor(x)), int(math.Floor(y))
+				if xi < 0 || yi < 0 || xi >= gray.width || yi >= gray.height {
+					continue
+				}
+				luminance := int(gray.pix[yi*gray.width+xi])
+				// the white ring is 1 dot away from the finder border
+				if ring := min(i, j, 6-i, 6-j); ring == 1 {
+					light = append(light, luminance)
+				} else {
+					dark = append(dark, luminance)
+				}
+			}
+		}
+	}
+	if len(dark) == 0 || len(light) == 0 {
+		return 0, 0, false
+	}
+	return meanLuminance(dark), meanLuminance(light), true
+}
+
+// halfContrast returns half the luminance difference between the dark and light dots of the finder patterns
+// of the QR-code, as finderLevels measures them: samples that far from the threshold are read with full confidence.
+// The difference is at least minFinderContrast, and a quarter of the luminance range if it cannot be measured.
+func halfContrast(gray *grayImage, transform homography, size int) float64 {
+	darkMean, lightMean, ok := finderLevels(gray, transform, size)
+	if !ok {
+		return 64
+	}
+	return float64(max(lightMean-darkMean, minFinderContrast)) / 2
+}
+
+// minFinderContrast is the minimum luminance difference between the dark and light dots of the finder patterns.
+const minFinderContrast = 32
+
+// meanLuminance returns the mean of the given luminance values.
+func meanLuminance(values []int) int {
+	sum := 0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / len(values)
+}
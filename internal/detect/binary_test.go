@@ -0,0 +1,110 @@
+package detect
+
+import (
+	"testing"
+)
+
+func TestOtsuThreshold(t *testing.T) {
+	tests := []struct {
+		name        string
+		pix         []uint8
+		minExpected int
+		maxExpected int
+	}{
+		{
+			name:        "black and white",
+			pix:         []uint8{0, 0, 0, 255, 255},
+			minExpected: 1,
+			maxExpected: 255,
+		},
+		{
+			name:        "dim light",
+			pix:         []uint8{18, 20, 22, 20, 68, 70, 72, 70},
+			minExpected: 23,
+			maxExpected: 68,
+		},
+		{
+			name:        "dark code on gray background",
+			pix:         []uint8{10, 10, 12, 240, 245, 240, 150, 150, 150, 150},
+			minExpected: 13,
+			maxExpected: 150,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gray := &grayImage{width: len(test.pix), height: 1, pix: test.pix}
+			threshold := otsuThreshold(gray)
+			if threshold < test.minExpected || threshold > test.maxExpected {
+				t.Errorf("expected threshold between %d and %d but got %d", test.minExpected, test.maxExpected, threshold)
+			}
+		})
+	}
+}
+
+func TestBinarizeLocal(t *testing.T) {
+	// a checkerboard of 4x4 squares, much darker on the right side
+	const size = 64
+	gray := &grayImage{width: size, height: size, pix: make([]uint8, size*size)}
+	for y := range size {
+		for x := range size {
+			luminance := 40
+			if (x/4+y/4)%2 == 0 {
+				luminance = 220
+			}
+			if x >= size/2 {
+				luminance /= 4
+			}
+			gray.pix[y*size+x] = uint8(luminance)
+		}
+	}
+
+	for _, method := range []Binarization{BinarizationMean, BinarizationSauvola} {
+		t.Run(method.String(), func(t *testing.T) {
+			b := binarize(gray, method)
+			errors := 0
+			for y := range size {
+				for x := range size {
+					if expected := (x/4+y/4)%2 != 0; b.at(x, y) != expected {
+						errors++
+					}
+				}
+			}
+			if errors > size*size/20 { // around the shadow edge
+				t.Errorf("expected less than 5%% of wrong pixels but got %d/%d", errors, size*size)
+			}
+		})
+	}
+}
+
+func TestBinarizationUnmarshalText(t *testing.T) {
+	tests := []struct {
+		text          string
+		expected      Binarization
+		expectedError bool
+	}{
+		{text: "otsu", expected: BinarizationOtsu},
+		{text: "mean", expected: BinarizationMean},
+		{text: "sauvola", expected: BinarizationSauvola},
+		{text: "niblack", expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			var method Binarization
+			err := method.UnmarshalText([]byte(test.text))
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got %s", method)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if method != test.expected {
+				t.Errorf("expected %s but got %s", test.expected, method)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package detect
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+)
+
+// finderScanlines are the scanlines measuring each finder pattern, as directions along the code axes and offsets
+// from its center perpendicular to them (in dots): lines along the axes cross the central black square,
+// diagonals go through the center only.
+var finderScanlines = [8]struct{ du, dv, offset float64 }{
+	{1, 0, -1}, {1, 0, 0}, {1, 0, 1},
+	{0, 1, -1}, {0, 1, 0}, {0, 1, 1},
+	{1, 1, 0}, {1, -1, 0},
+}
+
+// measureDots estimates the dots size (in pixels) and the dimension (in dots) of the QR-code filling the given image,
+// from the finder patterns found in its top-left, top-right and bottom-left corners.
+// It returns false if no finder can be measured.
+func measureDots(b *bitmap) (float64, int, bool) {
+	candidates := findFinderPatterns(b)
+	finders := make([]finderPattern, 0, 3)
+	for _, corner := range [3][2]float64{{0, 0}, {float64(b.width), 0}, {0, float64(b.height)}} {
+		finder, ok := finderInCorner(candidates, corner)
+		if !ok {
+			slog.Debug(fmt.Sprintf("No finder pattern found in corner (%.0f, %.0f)", corner[0], corner[1]))
+			continue
+		}
+		finders = append(finders, finder)
+	}
+
+	best, ok := voteDimension(b, finders, [2]float64{1, 0}, [2]float64{0, 1}, func(moduleSize float64) float64 {
+		return float64(b.width+b.height) / (2 * moduleSize)
+	})
+	if !ok {
+		return 0, 0, false
+	}
+	return float64(b.height) / float64(best), best, true
+}
+
+// voteDimension measures each of the given finders along several scanlines, parallel to the code axes u and v
+// (unit vectors in pixels) and to their diagonals: each measure of the dots size votes for the valid dimension
+// closest to the one computed from it by the given function, and the dimension with the most votes wins.
+// It returns false if no finder can be measured.
+func voteDimension(b *bitmap, finders []finderPattern, u, v [2]float64, dimension func(moduleSize float64) float64) (int, bool) {
+	votes := make(map[int]int)
+	for _, finder := range finders {
+		for _, scanline := range finderScanlines {
+			dx, dy := scanline.du*u[0]+scanline.dv*v[0], scanline.du*u[1]+scanline.dv*v[1]
+			// the offset is perpendicular to the scanline, i.e. along the other axis
+			offsetX := scanline.offset * (scanline.dv*u[0] + scanline.du*v[0]) * finder.moduleSize
+			offsetY := scanline.offset * (scanline.dv*u[1] + scanline.du*v[1]) * finder.moduleSize
+			counts, ok := runsAlong(b, finder.x+offsetX, finder.y+offsetY, dx, dy, 4*maxModules)
+			if !ok || !isFinderRatio(counts) {
+				continue
+			}
+			total := 0
+			for _, count := range counts {
+				total += count
+			}
+			// along diagonals, each step moves by one pixel along both axes: runs are as long as along the axes
+			moduleSize := float64(total) / 7
+			votes[validDimension(dimension(moduleSize))]++
+		}
+	}
+	if len(votes) == 0 {
+		return 0, false
+	}
+
+	dimensions := make([]int, 0, len(votes))
+	for dimension := range votes {
+		dimensions = append(dimensions, dimension)
+	}
+	slices.Sort(dimensions)
+	best := dimensions[0]
+	for _, dimension := range dimensions {
+		if votes[dimension] > votes[best] {
+			best = dimension
+		}
+	}
+	slog.Debug(fmt.Sprintf("Votes for QR-code dimension: %v", votes))
+
+	return best, true
+}
+
+// runsAlong counts the steps of (dx, dy) pixels crossing each of the 5 runs of a finder pattern, along the line
+// going through (x, y) in its central black run. It returns false if a run is empty or longer than maxCount steps.
+func runsAlong(b *bitmap, x, y, dx, dy float64, maxCount int) ([5]int, bool) {
+	var counts [5]int
+	isBlack := func(i int) bool {
+		return b.at(int(math.Floor(x+float64(i)*dx)), int(math.Floor(y+float64(i)*dy)))
+	}
+	if !isBlack(0) {
+		return counts, false
+	}
+
+	// backwards then forwards from the center: central black run, then white and black runs
+	for _, direction := range []int{-1, 1} {
+		i := max(direction, 0) // the center belongs to the backwards run only
+		for ; isBlack(direction*i) && counts[2] < maxCount; i++ {
+			counts[2]++
+		}
+		for k, black := range []bool{false, true} {
+			run := 2 + direction*(k+1)
+			for ; isBlack(direction*i) == black && counts[run] < maxCount; i++ {
+				counts[run]++
+			}
+		}
+	}
+
+	for _, count := range counts {
+		if count == 0 || count >= maxCount {
+			return counts, false
+		}
+	}
+	return counts, true
+}
+
+// finderInCorner returns the candidate finder pattern closest to the given corner of the image, whose center
+// is within 7 dots of the corner in both directions (it should be 3.5 dots away).
+func finderInCorner(candidates []finderPattern, corner [2]float64) (finderPattern, bool) {
+	best, bestDistance := finderPattern{}, math.Inf(1)
+	for _, candidate := range candidates {
+		dx, dy := math.Abs(candidate.x-corner[0]), math.Abs(candidate.y-corner[1])
+		if dx > 7*candidate.moduleSize || dy > 7*candidate.moduleSize {
+			continue
+		}
+		if d := math.Hypot(dx, dy); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best, !math.IsInf(bestDistance, 1)
+}
+
+// validDimension returns the dimension of a QR-code (17+4*version dots) closest to the given one.
+func validDimension(dimension float64) int {
+	return int(17 + 4*versionFromDimension(dimension))
+}
+
+// versionFromDimension returns the version of the QR-code whose dimension is the closest to the given one (in dots).
+func versionFromDimension(dimension float64) uint {
+	version := math.Round((dimension - 17) / 4)
+	return uint(min(max(version, 1), 40))
+}
@@ -0,0 +1,180 @@
+package detect
+
+import (
+	"math"
+	"testing"
+)
+
+// newFunctionPatternsBitmap draws the finder and timing patterns of a QR-code with the given dimension,
+// filling a bitmap of the given size in pixels. Other dots are white.
+func newFunctionPatternsBitmap(dimension, width, height int) *bitmap {
+	b := &bitmap{width: width, height: height, black: make([]bool, width*height)}
+	for y := range height {
+		for x := range width {
+			b.black[y*width+x] = isFunctionPatternDot(dimension, y*dimension/height, x*dimension/width)
+		}
+	}
+	return b
+}
+
+// newRotatedFunctionPatternsBitmap draws the finder and timing patterns of a QR-code with the given dimension,
+// with dots of the given size in pixels, rotated clockwise by the given angle (in degrees) around the center
+// of a bitmap twice as large as the code.
+func newRotatedFunctionPatternsBitmap(dimension int, moduleSize, angle float64) *bitmap {
+	size := int(2 * float64(dimension) * moduleSize)
+	b := &bitmap{width: size, height: size, black: make([]bool, size*size)}
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	for y := range size {
+		for x := range size {
+			dx, dy := float64(x)-float64(size)/2, float64(y)-float64(size)/2
+			u := (dx*cos+dy*sin)/moduleSize + float64(dimension)/2
+			v := (-dx*sin+dy*cos)/moduleSize + float64(dimension)/2
+			if u < 0 || v < 0 || u >= float64(dimension) || v >= float64(dimension) {
+				continue
+			}
+			b.black[y*size+x] = isFunctionPatternDot(dimension, int(v), int(u))
+		}
+	}
+	return b
+}
+
+// isFunctionPatternDot returns whether the dot at row i and column j of a QR-code with the given dimension
+// is black, considering only its finder and timing patterns.
+func isFunctionPatternDot(dimension, i, j int) bool {
+	for _, corner := range [3][2]int{{0, 0}, {0, dimension - 7}, {dimension - 7, 0}} {
+		di, dj := i-corner[0], j-corner[1]
+		if di < 0 || dj < 0 || di >= 7 || dj >= 7 {
+			continue
+		}
+		ring := min(di, dj, 6-di, 6-dj)
+		return ring != 1
+	}
+	switch {
+	case i == 6 && j >= 8 && j < dimension-8:
+		return j%2 == 0
+	case j == 6 && i >= 8 && i < dimension-8:
+		return i%2 == 0
+	}
+	return false
+}
+
+func TestMeasureDots(t *testing.T) {
+	tests := []struct {
+		name              string
+		b                 *bitmap
+		expectedDimension int
+		expectedError     bool
+	}{
+		{
+			name:              "version 1",
+			b:                 newFunctionPatternsBitmap(21, 84, 84),
+			expectedDimension: 21,
+		},
+		{
+			name:              "version 7, fractional dots size",
+			b:                 newFunctionPatternsBitmap(45, 160, 160),
+			expectedDimension: 45,
+		},
+		{
+			name:              "version 30",
+			b:                 newFunctionPatternsBitmap(137, 411, 411),
+			expectedDimension: 137,
+		},
+		{
+			name:              "version 40",
+			b:                 newFunctionPatternsBitmap(177, 531, 531),
+			expectedDimension: 177,
+		},
+		{
+			name:              "stretched code",
+			b:                 newFunctionPatternsBitmap(25, 110, 90),
+			expectedDimension: 25,
+		},
+		{
+			name: "damaged first column",
+			b: func() *bitmap {
+				b := newFunctionPatternsBitmap(29, 116, 116)
+				for y := range 40 {
+					b.black[y*b.width] = false
+					b.black[y*b.width+1] = false
+				}
+				return b
+			}(),
+			expectedDimension: 29,
+		},
+		{
+			name:          "no finder",
+			b:             &bitmap{width: 84, height: 84, black: make([]bool, 84*84)},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			moduleSize, dimension, ok := measureDots(test.b)
+			if ok == test.expectedError {
+				t.Fatalf("expected error to be %t but got %t", test.expectedError, !ok)
+			}
+			if test.expectedError {
+				return
+			}
+
+			if dimension != test.expectedDimension {
+				t.Errorf("expected dimension %d but got %d", test.expectedDimension, dimension)
+			}
+			if expected := float64(test.b.height) / float64(test.expectedDimension); math.Abs(moduleSize-expected) > 1e-9 {
+				t.Errorf("expected dots size %f but got %f", expected, moduleSize)
+			}
+		})
+	}
+}
+
+func TestEstimateVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    uint
+		moduleSize float64
+		angle      float64
+	}{
+		{name: "version 2", version: 2, moduleSize: 4},
+		{name: "version 30", version: 30, moduleSize: 3},
+		{name: "version 40, fractional dots size", version: 40, moduleSize: 2.5},
+		{name: "version 32, rotated", version: 32, moduleSize: 3, angle: 30},
+		{name: "version 40, rotated by 45 degrees", version: 40, moduleSize: 3, angle: 45},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := newRotatedFunctionPatternsBitmap(int(17+4*test.version), test.moduleSize, test.angle)
+			finders, err := selectFinderPatterns(findFinderPatterns(b))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// finders module sizes are overestimated for rotated codes: the vote should not depend on them
+			moduleSize := (finders[0].moduleSize + finders[1].moduleSize + finders[2].moduleSize) / 3
+			if version := estimateVersion(b, finders, moduleSize); version != test.version {
+				t.Errorf("expected version %d but got %d", test.version, version)
+			}
+		})
+	}
+}
+
+func TestValidDimension(t *testing.T) {
+	tests := []struct {
+		dimension float64
+		expected  int
+	}{
+		{dimension: 21, expected: 21},
+		{dimension: 22.9, expected: 21},
+		{dimension: 23.1, expected: 25},
+		{dimension: 10, expected: 21},
+		{dimension: 200, expected: 177},
+	}
+
+	for _, test := range tests {
+		if got := validDimension(test.dimension); got != test.expected {
+			t.Errorf("expected %.1f to snap to %d but got %d", test.dimension, test.expected, got)
+		}
+	}
+}
@@ -1,13 +1,60 @@
+//go:build cgo
+
 package detect
 
 import (
+	"fmt"
 	"image"
 	"image/color"
+	"log/slog"
 
 	"gocv.io/x/gocv"
 )
 
-func SetMiniCodeInCorner(img *gocv.Mat, points []image.Point, width, height int) gocv.Mat {
+// SetMiniCodeInCorner projects the QR-code delimited by the given points into the top-left corner of the image,
+// and returns the projected region. The projected code is always upright: points are first ordered clockwise,
+// then the rotation of the code is found out from its finder patterns, and the projection is corrected accordingly.
+// The points are returned ordered clockwise, starting from the top-left corner of the code.
+func SetMiniCodeInCorner(img *gocv.Mat, points []image.Point, width, height int) (gocv.Mat, []image.Point) {
+	return SetMiniCodeAt(*img, img, points, image.Point{}, width, height)
+}
+
+// SetMiniCodeAt projects the QR-code delimited by the given points in the source image into the destination image,
+// as SetMiniCodeInCorner does, with the top-left corner of the projection at the given origin.
+// Source and destination may be the same image. When several codes are projected side by side, the source
+// should be left untouched, so that a projection does not cover another code.
+// If the projection does not fit in the destination image, it is left untouched and the projection is returned alone.
+func SetMiniCodeAt(src gocv.Mat, dst *gocv.Mat, points []image.Point, origin image.Point, width, height int) (gocv.Mat, []image.Point) {
+	points = orderClockwise(points)
+
+	// project into a separate image first, since the source and destination regions may overlap
+	miniCode := gocv.NewMat()
+	warpPerspective(src, &miniCode, points, width, height)
+
+	if miniCodeImage, err := miniCode.ToImage(); err == nil {
+		corner, err := topLeftCorner(miniCodeImage)
+		if err != nil {
+			slog.Debug(fmt.Sprintf("QR-code orientation not found: %v", err))
+		} else if corner != 0 {
+			slog.Debug(fmt.Sprintf("QR-code is rotated by %d degrees", 90*corner))
+			points = rotatePoints(points, corner)
+			warpPerspective(src, &miniCode, points, width, height)
+		}
+	}
+
+	region := image.Rect(origin.X, origin.Y, origin.X+width-1, origin.Y+height-1)
+	if !region.In(image.Rect(0, 0, dst.Cols(), dst.Rows())) {
+		return miniCode, points
+	}
+	defer miniCode.Close()
+	rectangle := dst.Region(region)
+	miniCode.CopyTo(&rectangle)
+	return rectangle, points
+}
+
+// warpPerspective projects the quadrilateral delimited by the given points (clockwise, starting from the top-left one)
+// from the source image into a width x height destination image.
+func warpPerspective(src gocv.Mat, dst *gocv.Mat, points []image.Point, width, height int) {
 	originVector := gocv.NewPointVectorFromPoints(points)
 	defer originVector.Close()
 	destinationVector := gocv.NewPointVectorFromPoints([]image.Point{
@@ -19,10 +66,8 @@ func SetMiniCodeInCorner(img *gocv.Mat, points []image.Point, width, height int)
 	defer destinationVector.Close()
 	transform := gocv.GetPerspectiveTransform(originVector, destinationVector)
 	defer transform.Close()
-	rectangle := (*img).Region(image.Rect(0, 0, width-1, height-1))
 
-	gocv.WarpPerspective(*img, &rectangle, transform, image.Point{X: width - 1, Y: height - 1})
-	return rectangle
+	gocv.WarpPerspective(src, dst, transform, image.Point{X: width - 1, Y: height - 1})
 }
 
 func OutlineQRCode(img *gocv.Mat, points []image.Point, color color.RGBA, width int) {
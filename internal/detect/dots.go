@@ -1,124 +1,71 @@
+//go:build cgo
+
 package detect
 
 import (
 	"fmt"
 	"log/slog"
-	"math"
 
 	"gocv.io/x/gocv"
 )
 
-// QRCode is the representation of the code: 2-dimensional array of dots
-// "true" means black dot, "false" means
-type QRCode [][]bool
-
-const (
-	luminosityThreshold         = 500
-	luminosityPersistenceOffset = 0 // used to favor sequence color persistence if in doubt
-)
-
-// GetDots scans the input image pixels to try to extract QR-code dots.
-// Returns the grid of dots (true is black), and a boolean telling whether extraction was successful.
-func GetDots(img gocv.Mat) (QRCode, bool) {
-	firstColumn := getFirstColumnSequences(img)
-	slog.Debug(fmt.Sprintf("First column: %v", firstColumn))
+// GetDots scans the input image pixels to try to extract QR-code dots, telling black pixels from white ones
+// with the given binarization method. Light-on-dark codes are read as well: their dots are returned the right way up,
+// light dots being black.
+// Returns the grid of dots with the confidence of their reading, whether the code is light on dark,
+// and a boolean telling whether extraction was successful.
+func GetDots(img gocv.Mat, method Binarization) (SoftQRCode, bool, bool) {
+	miniCodeImage, err := img.ToImage()
+	if err != nil {
+		slog.Debug(fmt.Sprintf("QR-code image cannot be converted: %v", err))
+		return nil, false, false
+	}
+	gray := newGrayImage(miniCodeImage)
 
-	firstColumnStartsAndEndsInBlack := len(firstColumn) >= 3 && len(firstColumn)%2 == 1
-	firstAndLastBlocksHaveSimilarLength := len(firstColumn) >= 3 && nearlyEquals(firstColumn[0], firstColumn[len(firstColumn)-1], 5)
-	if !firstColumnStartsAndEndsInBlack || !firstAndLastBlocksHaveSimilarLength {
-		return nil, false
+	dots, ok := readDots(gray, method)
+	if ok {
+		return dots, false, true
 	}
-	scale := float64(firstColumn[0]) / 7. // a marker is 7 dots high
-	height := int(math.Round(float64(img.Rows()) / scale))
-	if height%2 == 0 {
-		return nil, false
+	// the finder patterns of light-on-dark codes are light: look for them in the negative image
+	dots, ok = readDots(gray.negative(), method)
+	if !ok {
+		return nil, false, false
 	}
-
-	slog.Info(fmt.Sprintf("Dots are %f pixels wide", scale))
-	dots := scanDots(img, scale)
-	return dots, true
+	slog.Debug("QR-code is light on dark")
+	return dots, true, true
 }
 
-// scanDots scans the input image step by step according to the given scale (dot size in pixel),
-// and constructs the dots grid
-func scanDots(img gocv.Mat, scale float64) QRCode {
-	dots := make(QRCode, 0)
+// readDots extracts the dots of the dark-on-light QR-code filling the given luminance image.
+func readDots(gray *grayImage, method Binarization) (SoftQRCode, bool) {
+	b := binarize(gray, method)
 
-	i, j, row, col := 0, 0, 0, 0
-	for {
-		row = indexToCoordinate(i, scale)
-		if row >= img.Rows() {
-			break
-		}
-		line := make([]bool, 0)
-		for {
-			col = indexToCoordinate(j, scale)
-			if col >= img.Cols() {
-				break
-			}
-			vec := img.GetVecbAt(row, col)
-			pixelLuminosity := int(vec[0]) + int(vec[1]) + int(vec[2])
-			pixelIsBlack := true
-			if pixelLuminosity >= luminosityThreshold {
-				pixelIsBlack = false
-			}
-			line = append(line, pixelIsBlack)
-			j++
-		}
-		dots = append(dots, line)
-		i++
-		j = 0
+	scale, height, ok := measureDots(b)
+	if !ok {
+		return nil, false
 	}
 
-	return dots
-}
-
-func indexToCoordinate(i int, scale float64) int {
-	return int(math.Round(
-		(float64(i) + 0.5) * scale,
-	))
-}
-
-// getFirstColumnSequences returns a sequence of luminosity values corresponding to pixels in the QR-code first column
-func getFirstColumnSequences(img gocv.Mat) []int {
-	sequences := make([]int, 0)
-	const offset = 1
-
-	isFirstSequence := true
-	currentSequenceIsBlack := false
-	currentSequenceLength := 0
-
-	for row := offset; row < img.Rows()-offset; row++ {
-		vec := img.GetVecbAt(row, offset)
-		pixelLuminosity := int(vec[0]) + int(vec[1]) + int(vec[2])
-
-		threshold := luminosityThreshold
-		if currentSequenceIsBlack {
-			threshold += luminosityPersistenceOffset
-		} else {
-			threshold -= luminosityPersistenceOffset
-		}
-		pixelIsBlack := true
-		if pixelLuminosity >= threshold {
-			pixelIsBlack = false
-		}
-
-		slog.Debug(fmt.Sprintf("[row %d] lum: %v / black: %v / sequence: %v", row, pixelLuminosity, pixelIsBlack, currentSequenceLength))
-		if pixelIsBlack == currentSequenceIsBlack {
-			currentSequenceLength++
-		} else {
-			if isFirstSequence && !currentSequenceIsBlack {
-				isFirstSequence = false
-			} else {
-				sequences = append(sequences, currentSequenceLength)
-			}
-			currentSequenceIsBlack = !currentSequenceIsBlack
-			currentSequenceLength = 1
-		}
+	size := float64(height) * scale
+	transform, err := newHomography(
+		[4][2]float64{{0, 0}, {float64(height), 0}, {0, float64(height)}, {float64(height), float64(height)}},
+		[4][2]float64{{0, 0}, {size, 0}, {0, size}, {size, size}},
+	)
+	if err != nil {
+		slog.Debug(fmt.Sprintf("QR-code transformation cannot be computed: %v", err))
+		return nil, false
 	}
-	if !isFirstSequence {
-		sequences = append(sequences, currentSequenceLength)
+	if method == BinarizationOtsu {
+		// the global threshold is computed from the whole image: refine it from the finder patterns
+		if threshold, ok := finderThreshold(gray, transform, height); ok {
+			slog.Debug(fmt.Sprintf("Luminance threshold from finder patterns is %d", threshold))
+			b = binarizeGlobal(gray, threshold)
+		}
 	}
 
-	return sequences
+	slog.Info(fmt.Sprintf("Dots are %f pixels wide", scale))
+	// the dots may not be evenly spaced: starting from the code filling the image, fit the dots grid
+	// to the timing and alignment patterns
+	scaleX, scaleY := float64(b.width)/float64(height), float64(b.height)/float64(height)
+	grid := fitGrid(b, func(x, y float64) (float64, float64) { return x * scaleX, y * scaleY }, height)
+	dots := sampleSoftDots(gray, b, halfContrast(gray, transform, height), grid.toImage, height)
+	return dots, true
 }
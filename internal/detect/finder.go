@@ -0,0 +1,281 @@
+package detect
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+// Inspired from ZXing's FinderPatternFinder:
+// https://github.com/zxing/zxing/blob/master/core/src/main/java/com/google/zxing/qrcode/detector/FinderPatternFinder.java
+
+// maxModules is the size of the largest QR-code (version 40), in dots.
+const maxModules = 177
+
+// maxCandidates is the number of finder candidates considered when looking for the three finders of the QR-code.
+const maxCandidates = 12
+
+// finderPattern is a candidate finder pattern found in the image.
+type finderPattern struct {
+	// x and y are the coordinates of the center of the pattern, in pixels.
+	x, y float64
+	// moduleSize is the estimated size of a dot, in pixels.
+	moduleSize float64
+	// count is the number of scanlines in which the pattern has been found.
+	count int
+}
+
+// findFinderPatterns scans the image line by line, looking for sequences of black, white, black, white and black pixels
+// with ratios 1:1:3:1:1, which is the signature of finder patterns whatever their orientation.
+// Each sequence found is confirmed by the same check in the vertical direction, and candidates found at
+// the same place in several lines are merged together.
+func findFinderPatterns(b *bitmap) []finderPattern {
+	candidates := make([]finderPattern, 0, 8)
+	skip := max(1, 3*b.height/(4*maxModules)) // the center of a finder is at least 3 lines high in the smallest codes
+
+	for y := skip - 1; y < b.height; y += skip {
+		var counts [5]int
+		state := 0
+		for x := 0; x <= b.width; x++ {
+			if b.at(x, y) {
+				if state%2 == 1 { // counting white pixels
+					state++
+				}
+				counts[state]++
+				continue
+			}
+
+			if state%2 == 1 { // counting white pixels
+				counts[state]++
+				continue
+			}
+			if state < 4 {
+				state++
+				counts[state]++
+				continue
+			}
+
+			// 5 runs found, check the ratios then look for the next pattern
+			if isFinderRatio(counts) {
+				centerX := x - counts[4] - counts[3] - counts[2]/2
+				if candidate, ok := crossCheckFinder(b, centerX, y); ok {
+					candidates = addCandidate(candidates, candidate)
+				}
+			}
+			counts = [5]int{counts[2], counts[3], counts[4], 1, 0}
+			state = 3
+		}
+	}
+
+	return candidates
+}
+
+// isFinderRatio returns whether the given runs lengths follow the 1:1:3:1:1 ratio of finder patterns,
+// with a tolerance of 50% on each run.
+func isFinderRatio(counts [5]int) bool {
+	total := 0
+	for _, count := range counts {
+		if count == 0 {
+			return false
+		}
+		total += count
+	}
+	if total < 7 {
+		return false
+	}
+
+	moduleSize := float64(total) / 7
+	variance := moduleSize / 2
+	return math.Abs(moduleSize-float64(counts[0])) < variance &&
+		math.Abs(moduleSize-float64(counts[1])) < variance &&
+		math.Abs(3*moduleSize-float64(counts[2])) < 3*variance &&
+		math.Abs(moduleSize-float64(counts[3])) < variance &&
+		math.Abs(moduleSize-float64(counts[4])) < variance
+}
+
+// crossCheckFinder confirms the finder pattern candidate found around (x, y), by checking the ratios
+// vertically then horizontally again, and returns the refined candidate.
+func crossCheckFinder(b *bitmap, x, y int) (finderPattern, bool) {
+	verticalCounts, centerY, ok := runsAcross(b, x, y, 0, 1, 4*maxModules)
+	if !ok || !isFinderRatio(verticalCounts) {
+		return finderPattern{}, false
+	}
+	horizontalCounts, centerX, ok := runsAcross(b, x, int(centerY), 1, 0, 4*maxModules)
+	if !ok || !isFinderRatio(horizontalCounts) {
+		return finderPattern{}, false
+	}
+
+	total := 0
+	for k := range 5 {
+		total += verticalCounts[k] + horizontalCounts[k]
+	}
+	return finderPattern{
+		x:          centerX,
+		y:          centerY,
+		moduleSize: float64(total) / 14,
+		count:      1,
+	}, true
+}
+
+// runsAcross measures the 5 runs of alternating colors centered on the black run containing (x, y),
+// in direction (dx, dy): the central run, then 2 runs on each side, none of them longer than maxCount pixels.
+// It returns the runs lengths, and the coordinate of the center of the central run along the direction
+// (x when the direction is horizontal, y otherwise).
+func runsAcross(b *bitmap, x, y, dx, dy int, maxCount int) ([5]int, float64, bool) {
+	var counts [5]int
+	if !b.at(x, y) {
+		return counts, 0, false
+	}
+
+	// backwards from the center: central black run, then white and black runs
+	i := 0
+	for ; b.at(x-i*dx, y-i*dy) && counts[2] < maxCount; i++ {
+		counts[2]++
+	}
+	start := i - 1
+	for k, black := range []bool{false, true} {
+		for ; i < maxCount && b.at(x-i*dx, y-i*dy) == black && insideBitmap(b, x-i*dx, y-i*dy); i++ {
+			counts[1-k]++
+		}
+	}
+
+	// forwards from the center
+	i = 1
+	for ; b.at(x+i*dx, y+i*dy) && counts[2] < maxCount; i++ {
+		counts[2]++
+	}
+	end := i - 1
+	for k, black := range []bool{false, true} {
+		for ; i < maxCount && b.at(x+i*dx, y+i*dy) == black && insideBitmap(b, x+i*dx, y+i*dy); i++ {
+			counts[3+k]++
+		}
+	}
+
+	for _, count := range counts {
+		if count == 0 || count >= maxCount {
+			return counts, 0, false
+		}
+	}
+
+	center := float64(x*dx+y*dy) + float64(end-start)/2
+	if dx != 0 && dy != 0 {
+		center = float64(y) + float64(end-start)/2 // diagonal: use the y coordinate
+	}
+	return counts, center + 0.5, true
+}
+
+func insideBitmap(b *bitmap, x, y int) bool {
+	return x >= 0 && y >= 0 && x < b.width && y < b.height
+}
+
+// addCandidate adds the candidate to the list, or merges it with an existing candidate found at the same place
+// (the position and module size are then averaged).
+func addCandidate(candidates []finderPattern, candidate finderPattern) []finderPattern {
+	for i, c := range candidates {
+		if math.Abs(c.x-candidate.x) > c.moduleSize || math.Abs(c.y-candidate.y) > c.moduleSize {
+			continue
+		}
+		if math.Abs(c.moduleSize-candidate.moduleSize) > math.Max(1, c.moduleSize/4) {
+			continue
+		}
+		count := float64(c.count)
+		candidates[i] = finderPattern{
+			x:          (c.x*count + candidate.x) / (count + 1),
+			y:          (c.y*count + candidate.y) / (count + 1),
+			moduleSize: (c.moduleSize*count + candidate.moduleSize) / (count + 1),
+			count:      c.count + 1,
+		}
+		return candidates
+	}
+	return append(candidates, candidate)
+}
+
+// selectFinderPatterns selects, among the candidates, the three finders of a QR-code: they have similar
+// module sizes, and form a right isosceles triangle.
+// They are returned in the following order: top-left (at the right angle), top-right and bottom-left,
+// whatever the orientation of the code in the image.
+func selectFinderPatterns(candidates []finderPattern) ([3]finderPattern, error) {
+	if len(candidates) < 3 {
+		return [3]finderPattern{}, errors.New("not enough finder patterns found in image")
+	}
+
+	// favor candidates confirmed by several scanlines
+	candidates = slices.Clone(candidates)
+	slices.SortStableFunc(candidates, func(a, b finderPattern) int { return b.count - a.count })
+	candidates = candidates[:min(len(candidates), maxCandidates)]
+
+	var best [3]finderPattern
+	bestScore := math.Inf(1)
+	for i := range candidates {
+		for j := i + 1; j < len(candidates); j++ {
+			for k := j + 1; k < len(candidates); k++ {
+				triple := [3]finderPattern{candidates[i], candidates[j], candidates[k]}
+				if score := triangleScore(triple); score < bestScore {
+					best, bestScore = triple, score
+				}
+			}
+		}
+	}
+	if math.IsInf(bestScore, 1) {
+		return [3]finderPattern{}, errors.New("no finder patterns forming a QR-code found in image")
+	}
+
+	return orderFinderPatterns(best), nil
+}
+
+// triangleScore evaluates how likely the three finders belong to the same QR-code: the lower, the better.
+// It returns +Inf for impossible configurations.
+func triangleScore(triple [3]finderPattern) float64 {
+	minModuleSize := math.Min(triple[0].moduleSize, math.Min(triple[1].moduleSize, triple[2].moduleSize))
+	maxModuleSize := math.Max(triple[0].moduleSize, math.Max(triple[1].moduleSize, triple[2].moduleSize))
+	if maxModuleSize > 1.5*minModuleSize {
+		return math.Inf(1)
+	}
+
+	sides := []float64{
+		distance(triple[0], triple[1]),
+		distance(triple[1], triple[2]),
+		distance(triple[2], triple[0]),
+	}
+	slices.Sort(sides)
+	a, b, c := sides[0], sides[1], sides[2]
+
+	// finders are between 14 (version 1) and 170 (version 40) dots apart
+	moduleSize := (minModuleSize + maxModuleSize) / 2
+	if a < 10*moduleSize || b > 200*moduleSize {
+		return math.Inf(1)
+	}
+
+	rightAngle := math.Abs(c*c/(a*a+b*b) - 1) // Pythagoras
+	isosceles := math.Abs(a/b - 1)
+	if rightAngle > 0.5 || isosceles > 0.5 {
+		return math.Inf(1)
+	}
+	return rightAngle + isosceles + (maxModuleSize-minModuleSize)/maxModuleSize
+}
+
+// orderFinderPatterns returns the three finders in the following order: top-left, top-right, bottom-left.
+// The top-left one is opposite the longest side of the triangle, and the other two are ordered so that
+// going from top-right to bottom-left around top-left is clockwise (in image coordinates, y axis pointing downwards).
+func orderFinderPatterns(triple [3]finderPattern) [3]finderPattern {
+	d01, d12, d20 := distance(triple[0], triple[1]), distance(triple[1], triple[2]), distance(triple[2], triple[0])
+	var topLeft, p1, p2 finderPattern
+	switch {
+	case d12 >= d01 && d12 >= d20:
+		topLeft, p1, p2 = triple[0], triple[1], triple[2]
+	case d20 >= d01 && d20 >= d12:
+		topLeft, p1, p2 = triple[1], triple[2], triple[0]
+	default:
+		topLeft, p1, p2 = triple[2], triple[0], triple[1]
+	}
+
+	crossProduct := (p1.x-topLeft.x)*(p2.y-topLeft.y) - (p1.y-topLeft.y)*(p2.x-topLeft.x)
+	if crossProduct < 0 {
+		p1, p2 = p2, p1
+	}
+	return [3]finderPattern{topLeft, p1, p2}
+}
+
+func distance(p1, p2 finderPattern) float64 {
+	return math.Hypot(p1.x-p2.x, p1.y-p2.y)
+}
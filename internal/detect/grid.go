@@ -0,0 +1,179 @@
+package detect
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+)
+
+// dotGrid maps dots coordinates to image coordinates, correcting a base transformation with the actual positions
+// of the timing and alignment patterns: residual perspective or lens distortion makes dots unevenly spaced,
+// and sampling them at regular intervals drifts away from their centers far from the finders.
+type dotGrid struct {
+	base func(x, y float64) (float64, float64)
+	// columns and rows are the measured positions of the boundaries between dots 7 and size-7 along the timing
+	// patterns, in base dots coordinates, or nil if they could not be measured
+	columns, rows []float64
+	// alignments are the rows and columns of the alignment patterns centers, and shifts the offsets (in pixels)
+	// between their expected and actual positions in the image, indexed by row then column
+	alignments []int
+	shifts     [][][2]float64
+}
+
+// timingStep is the distance between two samples along the timing patterns, in dots.
+const timingStep = 0.05
+
+// fitGrid locates the dots of the QR-code of the given size, starting from the given transformation from dots
+// coordinates to image coordinates: dots boundaries are measured along both timing patterns, then the centers
+// of the alignment patterns (if any) are searched around their expected positions.
+func fitGrid(b *bitmap, base func(x, y float64) (float64, float64), size int) dotGrid {
+	g := dotGrid{base: base}
+
+	horizontal := func(t float64) bool { return sampleTimingAt(b, base, t, 6.5, 0, 1) }
+	vertical := func(t float64) bool { return sampleTimingAt(b, base, 6.5, t, 1, 0) }
+	var ok bool
+	if g.columns, ok = timingBoundaries(horizontal, size); !ok {
+		slog.Debug("Horizontal timing pattern cannot be measured, assume evenly spaced columns")
+	}
+	if g.rows, ok = timingBoundaries(vertical, size); !ok {
+		slog.Debug("Vertical timing pattern cannot be measured, assume evenly spaced rows")
+	}
+
+	g.alignments = AlignmentPatternPositions(uint(size-17) / 4)
+	g.shifts = make([][][2]float64, len(g.alignments))
+	for i := range g.shifts {
+		g.shifts[i] = make([][2]float64, len(g.alignments))
+	}
+	for i, row := range g.alignments {
+		for j, col := range g.alignments {
+			if i == 0 && j == 0 || i == 0 && j == len(g.alignments)-1 || i == len(g.alignments)-1 && j == 0 {
+				continue // overlaps a finder pattern, on the timing patterns anyway
+			}
+			x, y := g.toImage(float64(col)+0.5, float64(row)+0.5)
+			nextX, nextY := g.toImage(float64(col)+1.5, float64(row)+0.5)
+			moduleSize := math.Hypot(nextX-x, nextY-y)
+			foundX, foundY, ok := findAlignmentPattern(b, x, y, moduleSize, 2*moduleSize)
+			if !ok {
+				continue
+			}
+			slog.Debug(fmt.Sprintf("Alignment pattern (%d, %d) found at (%.0f, %.0f), expected at (%.0f, %.0f)", row, col, foundX, foundY, x, y))
+			if math.Hypot(foundX-x, foundY-y) > 1 {
+				// the pattern center is measured from whole pixels: smaller offsets are rounding errors
+				g.shifts[i][j] = [2]float64{foundX - x, foundY - y}
+			}
+		}
+	}
+
+	return g
+}
+
+// sampleTimingAt tells whether the timing pattern is black at dots coordinates (x, y), from 3 samples across it,
+// in direction (dx, dy).
+func sampleTimingAt(b *bitmap, base func(x, y float64) (float64, float64), x, y, dx, dy float64) bool {
+	black := 0
+	for _, offset := range []float64{-0.25, 0, 0.25} {
+		px, py := base(x+offset*dx, y+offset*dy)
+		if b.at(int(math.Floor(px)), int(math.Floor(py))) {
+			black++
+		}
+	}
+	return black >= 2
+}
+
+// timingBoundaries measures the positions of the boundaries between dots 7 and size-7 along a timing pattern,
+// given the color of the pattern at any position t (in dots) along it.
+// The pattern starts and ends with the black border of the finders, with alternating white and black dots
+// in-between, starting with the white separator: size-13 color changes are expected.
+// Color changes which do not last a quarter of a dot are ignored, as noise, and each dot must be between half and
+// one and a half dot wide.
+func timingBoundaries(isBlack func(t float64) bool, size int) ([]float64, bool) {
+	boundaries := make([]float64, 0, size-13)
+	black := true
+	for t := 3.5; t < float64(size)-3.5; t += timingStep {
+		if isBlack(t) == black || !lasts(isBlack, t, !black) {
+			continue
+		}
+		boundaries = append(boundaries, t-timingStep/2)
+		black = !black
+	}
+	if len(boundaries) != size-13 {
+		slog.Debug(fmt.Sprintf("Found %d color changes along timing pattern, expected %d", len(boundaries), size-13))
+		return nil, false
+	}
+	for k := 1; k < len(boundaries); k++ {
+		if width := boundaries[k] - boundaries[k-1]; width < 0.5 || width > 1.5 {
+			// the pattern was not followed, other dots got in the way
+			slog.Debug(fmt.Sprintf("Dot %d along timing pattern is %.2f dots wide", k+6, width))
+			return nil, false
+		}
+	}
+	return boundaries, true
+}
+
+// lasts returns whether the color along the timing pattern remains the same for a quarter of a dot, starting at t.
+func lasts(isBlack func(t float64) bool, t float64, black bool) bool {
+	for d := 0.; d < 0.25; d += timingStep {
+		if isBlack(t+d) != black {
+			return false
+		}
+	}
+	return true
+}
+
+// toImage converts dots coordinates to image coordinates.
+func (g dotGrid) toImage(x, y float64) (float64, float64) {
+	px, py := g.base(remap(g.columns, x), remap(g.rows, y))
+	shiftX, shiftY := g.shift(x, y)
+	return px + shiftX, py + shiftY
+}
+
+// remap converts position t along a timing pattern (in dots) to the base dots coordinates, interpolating linearly
+// between the measured boundaries of dots 7 to size-7. The finders are located accurately by the base transformation:
+// their dots are kept untouched, except for the last one, which bridges the gap with the timing pattern.
+func remap(boundaries []float64, t float64) float64 {
+	if len(boundaries) == 0 {
+		return t
+	}
+	const first = 7
+	last := len(boundaries) - 1
+	end := float64(first + last)
+	switch {
+	case t < first-1 || t >= end+1:
+		return t
+	case t < first:
+		return first - 1 + (t-first+1)*(boundaries[0]-first+1)
+	case t >= end:
+		return boundaries[last] + (t-end)*(end+1-boundaries[last])
+	}
+	i := int(math.Floor(t)) - first
+	return boundaries[i] + (t-math.Floor(t))*(boundaries[i+1]-boundaries[i])
+}
+
+// shift returns the offset (in pixels) to apply at dots coordinates (x, y), interpolated bilinearly
+// from the offsets measured at the 4 closest alignment patterns.
+func (g dotGrid) shift(x, y float64) (float64, float64) {
+	if len(g.alignments) < 2 {
+		return 0, 0
+	}
+	i, u := latticeCell(g.alignments, y)
+	j, v := latticeCell(g.alignments, x)
+	var result [2]float64
+	for k := range result {
+		top := (1-v)*g.shifts[i][j][k] + v*g.shifts[i][j+1][k]
+		bottom := (1-v)*g.shifts[i+1][j][k] + v*g.shifts[i+1][j+1][k]
+		result[k] = (1-u)*top + u*bottom
+	}
+	return result[0], result[1]
+}
+
+// latticeCell returns the index of the interval between consecutive alignment patterns containing
+// position t (in dots), and the relative position of t in this interval, from 0 to 1.
+// Positions outside of the alignment patterns range are clamped to the first or last interval.
+func latticeCell(positions []int, t float64) (int, float64) {
+	i := 0
+	for i < len(positions)-2 && t > float64(positions[i+1])+0.5 {
+		i++
+	}
+	start, end := float64(positions[i])+0.5, float64(positions[i+1])+0.5
+	return i, min(max((t-start)/(end-start), 0), 1)
+}
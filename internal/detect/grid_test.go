@@ -0,0 +1,121 @@
+package detect
+
+import (
+	"math"
+	"testing"
+)
+
+// timingPattern returns the color along the timing pattern of a QR-code of the given size, whose dots boundaries
+// are at positions position(k), in dots.
+func timingPattern(size int, position func(k float64) float64) func(t float64) bool {
+	return func(t float64) bool {
+		k := 0
+		for k < size && position(float64(k+1)) <= t {
+			k++
+		}
+		return k < 7 || k >= size-7 || k%2 == 0
+	}
+}
+
+func TestTimingBoundaries(t *testing.T) {
+	uniform := func(k float64) float64 { return k }
+	stretched := func(k float64) float64 { return k + 0.003*k*k } // dots get wider far from the finder, a dot off at the end
+
+	tests := []struct {
+		name          string
+		size          int
+		isBlack       func(t float64) bool
+		expected      func(k float64) float64
+		expectedError bool
+	}{
+		{
+			name:     "evenly spaced dots",
+			size:     21,
+			isBlack:  timingPattern(21, uniform),
+			expected: uniform,
+		},
+		{
+			name:     "unevenly spaced dots",
+			size:     25,
+			isBlack:  timingPattern(25, stretched),
+			expected: stretched,
+		},
+		{
+			name: "noise in a dot",
+			size: 21,
+			isBlack: func(t float64) bool {
+				if t > 10.4 && t < 10.5 {
+					return true // thin black line across dot 10 (white)
+				}
+				return timingPattern(21, uniform)(t)
+			},
+			expected: uniform,
+		},
+		{
+			name: "missing dot",
+			size: 21,
+			isBlack: func(t float64) bool {
+				if t > 9 && t < 10 {
+					return true // dot 9 is black instead of white
+				}
+				return timingPattern(21, uniform)(t)
+			},
+			expectedError: true,
+		},
+		{
+			name:          "dots too wide",
+			size:          25,
+			isBlack:       timingPattern(25, func(k float64) float64 { return k + max(k-15, 0)*0.6 }),
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			boundaries, ok := timingBoundaries(test.isBlack, test.size)
+			if ok == test.expectedError {
+				t.Fatalf("expected error to be %t but got %t", test.expectedError, !ok)
+			}
+			if test.expectedError {
+				return
+			}
+
+			if len(boundaries) != test.size-13 {
+				t.Fatalf("expected %d boundaries but got %d", test.size-13, len(boundaries))
+			}
+			for i, boundary := range boundaries {
+				if expected := test.expected(float64(i + 7)); math.Abs(boundary-expected) > timingStep {
+					t.Errorf("expected boundary %d at %.2f but got %.2f", i+7, expected, boundary)
+				}
+			}
+		})
+	}
+}
+
+func TestRemap(t *testing.T) {
+	// version 1: boundaries of dots 7 to 14, dots 9 and 10 are wider
+	boundaries := []float64{7, 8, 9.2, 10.4, 11.4, 12.4, 13.4, 14.4}
+
+	tests := []struct {
+		name       string
+		boundaries []float64
+		t          float64
+		expected   float64
+	}{
+		{name: "timing pattern not measured", boundaries: nil, t: 10.5, expected: 10.5},
+		{name: "within the first finder", boundaries: boundaries, t: 3.5, expected: 3.5},
+		{name: "first timing dot", boundaries: boundaries, t: 7.5, expected: 7.5},
+		{name: "wider dot", boundaries: boundaries, t: 9.5, expected: 9.8},
+		{name: "after wider dots", boundaries: boundaries, t: 12.5, expected: 12.9},
+		{name: "last dot before the second finder", boundaries: boundaries, t: 14.5, expected: 14.7},
+		{name: "within the second finder", boundaries: boundaries, t: 17.5, expected: 17.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := remap(test.boundaries, test.t); math.Abs(got-test.expected) > 1e-9 {
+				t.Errorf("expected %.2f but got %.2f", test.expected, got)
+			}
+		})
+	}
+}
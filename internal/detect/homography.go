@@ -0,0 +1,61 @@
+package detect
+
+import (
+	"errors"
+	"math"
+)
+
+// Inspired from https://docs.opencv.org/4.x/d9/dab/tutorial_homography.html
+
+// homography is a perspective transformation of the plane, represented by a 3x3 matrix (row by row),
+// whose last coefficient is 1.
+type homography [9]float64
+
+// newHomography computes the perspective transformation which maps the 4 source points to the 4 destination points.
+// Each point gives 2 equations, hence 8 equations for the 8 unknown coefficients of the transformation.
+func newHomography(src, dst [4][2]float64) (homography, error) {
+	var system [8][9]float64 // augmented matrix of the linear system
+	for i := range 4 {
+		x, y := src[i][0], src[i][1]
+		u, v := dst[i][0], dst[i][1]
+		system[2*i] = [9]float64{x, y, 1, 0, 0, 0, -x * u, -y * u, u}
+		system[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -x * v, -y * v, v}
+	}
+
+	// Gaussian elimination, with partial pivoting
+	for col := range 8 {
+		pivot := col
+		for row := col + 1; row < 8; row++ {
+			if math.Abs(system[row][col]) > math.Abs(system[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(system[pivot][col]) < 1e-12 {
+			return homography{}, errors.New("degenerate points, no perspective transformation")
+		}
+		system[col], system[pivot] = system[pivot], system[col]
+
+		for row := range 8 {
+			if row == col {
+				continue
+			}
+			factor := system[row][col] / system[col][col]
+			for k := col; k < 9; k++ {
+				system[row][k] -= factor * system[col][k]
+			}
+		}
+	}
+
+	var h homography
+	for i := range 8 {
+		h[i] = system[i][8] / system[i][i]
+	}
+	h[8] = 1
+	return h, nil
+}
+
+// apply transforms the point (x, y).
+func (h homography) apply(x, y float64) (float64, float64) {
+	w := h[6]*x + h[7]*y + h[8]
+	return (h[0]*x + h[1]*y + h[2]) / w, (h[3]*x + h[4]*y + h[5]) / w
+}
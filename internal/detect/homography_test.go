@@ -0,0 +1,31 @@
+package detect
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewHomography(t *testing.T) {
+	src := [4][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	dst := [4][2]float64{{100, 50}, {180, 60}, {170, 150}, {90, 130}}
+
+	h, err := newHomography(src, dst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range src {
+		x, y := h.apply(src[i][0], src[i][1])
+		if math.Abs(x-dst[i][0]) > 1e-6 || math.Abs(y-dst[i][1]) > 1e-6 {
+			t.Errorf("expected point %v to be mapped to %v but got (%f, %f)", src[i], dst[i], x, y)
+		}
+	}
+}
+
+func TestNewHomography_Degenerate(t *testing.T) {
+	src := [4][2]float64{{0, 0}, {10, 0}, {20, 0}, {30, 0}}
+	dst := [4][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+
+	if _, err := newHomography(src, dst); err == nil {
+		t.Errorf("expected error for aligned points")
+	}
+}
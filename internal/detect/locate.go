@@ -0,0 +1,396 @@
+package detect
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"math"
+	"slices"
+)
+
+// FindQRCode detects a QR-code in the given image without OpenCV, from its finder patterns, then samples its dots.
+// The code may have any rotation, and perspective distortion within the limits given by the options.
+// Light-on-dark codes are found as well, when no dark-on-light code is: their dots are returned the right way up,
+// light dots being black.
+// Returns the dots with the confidence of their reading, the QR-code corners in the image (top-left, top-right,
+// bottom-right and bottom-left, in the QR-code orientation), and whether the code is light on dark.
+func FindQRCode(img image.Image, options Options) (SoftQRCode, []image.Point, bool, error) {
+	gray := newGrayImage(img)
+	dots, points, err := findQRCode(gray, img.Bounds().Min, options)
+	if err == nil {
+		return dots, points, false, nil
+	}
+
+	// the finder patterns of light-on-dark codes are light: look for them in the negative image
+	dots, points, negativeErr := findQRCode(gray.negative(), img.Bounds().Min, options)
+	if negativeErr != nil {
+		return nil, nil, false, err
+	}
+	slog.Debug("QR-code is light on dark")
+	return dots, points, true, nil
+}
+
+// findQRCode detects a dark-on-light QR-code in the given luminance image, whose top-left corner is origin.
+func findQRCode(gray *grayImage, origin image.Point, options Options) (SoftQRCode, []image.Point, error) {
+	b := binarize(gray, options.Binarization)
+
+	candidates := findFinderPatterns(b)
+	slog.Debug(fmt.Sprintf("Found %d finder pattern candidates", len(candidates)))
+	finders, err := selectFinderPatterns(candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return locateQRCode(gray, b, origin, finders, options)
+}
+
+// FindQRCodes detects all the QR-codes in the given image, as FindQRCode does: the best triple of finder patterns
+// is selected repeatedly among the remaining candidates, until no more triple forms a QR-code.
+// Light-on-dark codes are looked for in the negative image, and follow the dark-on-light ones.
+// Returns the dots of each QR-code found (the right way up), its corners in the image, and whether it is
+// light on dark, in the same order.
+func FindQRCodes(img image.Image, options Options) ([]SoftQRCode, [][]image.Point, []bool, error) {
+	gray := newGrayImage(img)
+	codes, corners, err := findQRCodes(gray, img.Bounds().Min, options)
+	inverted := make([]bool, len(codes))
+
+	negativeCodes, negativeCorners, _ := findQRCodes(gray.negative(), img.Bounds().Min, options)
+	for i, dots := range negativeCodes {
+		slog.Debug(fmt.Sprintf("QR-code at %v is light on dark", negativeCorners[i]))
+		codes = append(codes, dots)
+		corners = append(corners, negativeCorners[i])
+		inverted = append(inverted, true)
+	}
+
+	if len(codes) == 0 {
+		return nil, nil, nil, err
+	}
+	return codes, corners, inverted, nil
+}
+
+// findQRCodes detects all the dark-on-light QR-codes in the given luminance image, whose top-left corner is origin.
+func findQRCodes(gray *grayImage, origin image.Point, options Options) ([]SoftQRCode, [][]image.Point, error) {
+	b := binarize(gray, options.Binarization)
+
+	candidates := findFinderPatterns(b)
+	slog.Debug(fmt.Sprintf("Found %d finder pattern candidates", len(candidates)))
+
+	var codes []SoftQRCode
+	var corners [][]image.Point
+	err := errors.New("not enough finder patterns found in image")
+	for len(candidates) >= 3 {
+		var finders [3]finderPattern
+		finders, err = selectFinderPatterns(candidates)
+		if err != nil {
+			break
+		}
+		candidates = slices.DeleteFunc(candidates, func(c finderPattern) bool { return slices.Contains(finders[:], c) })
+
+		dots, points, locateErr := locateQRCode(gray, b, origin, finders, options)
+		if locateErr != nil {
+			slog.Debug(fmt.Sprintf("Finder patterns do not delimit a valid QR-code: %v", locateErr))
+			err = locateErr
+			continue
+		}
+		codes = append(codes, dots)
+		corners = append(corners, points)
+	}
+
+	if len(codes) == 0 {
+		return nil, nil, err
+	}
+	return codes, corners, nil
+}
+
+// locateQRCode samples the dots of the QR-code delimited by the given finders (top-left, top-right and bottom-left),
+// and computes its corners in the image, whose top-left corner is origin.
+// The dots are read from the luminance image, compared with the thresholds of the given binarized image,
+// or with a threshold refined from the finder patterns, depending on the binarization method.
+func locateQRCode(gray *grayImage, b *bitmap, origin image.Point, finders [3]finderPattern, options Options) (SoftQRCode, []image.Point, error) {
+	topLeft, topRight, bottomLeft := finders[0], finders[1], finders[2]
+
+	// finders module sizes are measured horizontally and vertically, which overestimates them for rotated codes:
+	// measure them again along the sides of the code
+	moduleSize := (moduleSizeAlong(b, topLeft, topRight) + moduleSizeAlong(b, topLeft, bottomLeft)) / 2
+	if moduleSize == 0 {
+		moduleSize = (topLeft.moduleSize + topRight.moduleSize + bottomLeft.moduleSize) / 3
+	}
+	estimatedVersion := estimateVersion(b, finders, moduleSize)
+	slog.Debug(fmt.Sprintf("Finders found at (%.0f, %.0f), (%.0f, %.0f), (%.0f, %.0f) / Dots are %f pixels wide / Version is about %d",
+		topLeft.x, topLeft.y, topRight.x, topRight.y, bottomLeft.x, bottomLeft.y, moduleSize, estimatedVersion))
+
+	// the module size is not accurate enough to tell neighbor versions apart for large codes:
+	// sample the code with each possible version, and keep the one whose version information can be decoded,
+	// or the one with the most regular timing patterns
+	var dots QRCode
+	var transform homography
+	bestScore := -1.
+	versions := candidateVersions(estimatedVersion)
+	if options.Version != 0 {
+		versions = []uint{options.Version}
+	}
+	for _, version := range versions {
+		candidateTransform, err := sampleTransform(b, finders, moduleSize, version)
+		if err != nil {
+			continue
+		}
+		candidateDots := sampleDots(b, candidateTransform, int(17+4*version))
+		// a block misread from a badly sampled code may be corrected to an unrelated version: ignore it
+		if informationVersion, ok := readVersion(candidateDots); ok && slices.Contains(versions, informationVersion) {
+			slog.Debug(fmt.Sprintf("Version information read when sampled as version %d: %d", version, informationVersion))
+			if informationVersion != version {
+				candidateTransform, err = sampleTransform(b, finders, moduleSize, informationVersion)
+				if err != nil {
+					continue
+				}
+				candidateDots = sampleDots(b, candidateTransform, int(17+4*informationVersion))
+			}
+			dots, transform = candidateDots, candidateTransform
+			break
+		}
+		if score := timingScore(candidateDots); score > bestScore {
+			dots, transform, bestScore = candidateDots, candidateTransform, score
+		}
+	}
+	if dots == nil {
+		return nil, nil, errors.New("QR-code cannot be sampled")
+	}
+
+	if options.Binarization == BinarizationOtsu {
+		// the global threshold is computed from the whole image: refine it from the finder patterns of the code itself
+		if threshold, ok := finderThreshold(gray, transform, len(dots)); ok {
+			slog.Debug(fmt.Sprintf("Luminance threshold from finder patterns is %d", threshold))
+			b = binarizeGlobal(gray, threshold)
+		}
+	}
+	// the homography is computed from 4 points only: fit the dots grid to the timing and alignment patterns
+	grid := fitGrid(b, transform.apply, len(dots))
+	softDots := sampleSoftDots(gray, b, halfContrast(gray, transform, len(dots)), grid.toImage, len(dots))
+
+	size := float64(len(dots))
+	corners := make([]image.Point, 0, 4)
+	for _, corner := range [4][2]float64{{0, 0}, {size, 0}, {size, size}, {0, size}} {
+		x, y := transform.apply(corner[0], corner[1])
+		if x < -moduleSize || y < -moduleSize || x > float64(b.width)+moduleSize || y > float64(b.height)+moduleSize {
+			return nil, nil, errors.New("QR-code is partially out of image")
+		}
+		corners = append(corners, image.Point{
+			X: origin.X + int(math.Round(x)),
+			Y: origin.Y + int(math.Round(y)),
+		})
+	}
+
+	if !validShape(corners, options) {
+		return nil, nil, errors.New("QR-code outline is too distorted")
+	}
+
+	return softDots, corners, nil
+}
+
+// topLeftCorner returns the corner of the image where the top-left finder of the QR-code it holds is located,
+// from 0 to 3 clockwise, starting from the top-left corner of the image.
+// It is used to find out the rotation of a QR-code which fills the image, dark on light or light on dark.
+func topLeftCorner(img image.Image) (int, error) {
+	gray := newGrayImage(img)
+	b := binarize(gray, BinarizationOtsu)
+	finders, err := selectFinderPatterns(findFinderPatterns(b))
+	if err != nil {
+		// light-on-dark code
+		b = binarize(gray.negative(), BinarizationOtsu)
+		finders, err = selectFinderPatterns(findFinderPatterns(b))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	left, top := finders[0].x < float64(b.width)/2, finders[0].y < float64(b.height)/2
+	switch {
+	case left && top:
+		return 0, nil
+	case top:
+		return 1, nil
+	case !left:
+		return 2, nil
+	}
+	return 3, nil
+}
+
+// sampleTransform computes the transformation from dots coordinates to image coordinates, for a QR-code
+// of the given version, from the finders centers and a 4th point: the bottom-right alignment pattern if found,
+// otherwise the estimated position of a 4th finder.
+func sampleTransform(b *bitmap, finders [3]finderPattern, moduleSize float64, version uint) (homography, error) {
+	topLeft, topRight, bottomLeft := finders[0], finders[1], finders[2]
+	size := float64(17 + 4*version)
+
+	src := [4][2]float64{{3.5, 3.5}, {size - 3.5, 3.5}, {3.5, size - 3.5}}
+	dst := [4][2]float64{{topLeft.x, topLeft.y}, {topRight.x, topRight.y}, {bottomLeft.x, bottomLeft.y}}
+
+	bottomRightX, bottomRightY := topRight.x-topLeft.x+bottomLeft.x, topRight.y-topLeft.y+bottomLeft.y
+	src[3], dst[3] = [2]float64{size - 3.5, size - 3.5}, [2]float64{bottomRightX, bottomRightY}
+	if version >= 2 {
+		// the alignment pattern center is 3 dots closer to the top-left corner than the 4th finder would be
+		correction := 1 - 3/(size-7)
+		estimatedX := topLeft.x + correction*(bottomRightX-topLeft.x)
+		estimatedY := topLeft.y + correction*(bottomRightY-topLeft.y)
+		for _, allowance := range []float64{4, 8, 16} {
+			if x, y, ok := findAlignmentPattern(b, estimatedX, estimatedY, moduleSize, allowance*moduleSize); ok {
+				slog.Debug(fmt.Sprintf("Alignment pattern found at (%.0f, %.0f)", x, y))
+				src[3], dst[3] = [2]float64{size - 6.5, size - 6.5}, [2]float64{x, y}
+				break
+			}
+		}
+	}
+
+	return newHomography(src, dst)
+}
+
+// timingScore returns the proportion of dots of both timing patterns matching the expected alternation
+// of black and white dots.
+func timingScore(dots QRCode) float64 {
+	size := len(dots)
+	matching := 0
+	for k := 8; k < size-8; k++ {
+		expected := k%2 == 0
+		if dots[6][k] == expected {
+			matching++
+		}
+		if dots[k][6] == expected {
+			matching++
+		}
+	}
+	return float64(matching) / float64(2*(size-16))
+}
+
+// maxVersionDeviation is the maximum difference between the version estimated from the finders
+// and the actual version of the QR-code.
+const maxVersionDeviation = 3
+
+// candidateVersions returns the versions to try when sampling a QR-code of the given estimated version,
+// from the closest to the farthest.
+func candidateVersions(estimatedVersion uint) []uint {
+	versions := []uint{estimatedVersion}
+	for deviation := uint(1); deviation <= maxVersionDeviation; deviation++ {
+		if estimatedVersion > deviation {
+			versions = append(versions, estimatedVersion-deviation)
+		}
+		if estimatedVersion+deviation <= 40 {
+			versions = append(versions, estimatedVersion+deviation)
+		}
+	}
+	return versions
+}
+
+// estimateVersion deduces the version from the distance between the finders, in dots.
+// Finders centers are size-7 dots apart: the dots size is voted from several measures of the finders along the code axes,
+// falling back to the given one if they cannot be measured.
+func estimateVersion(b *bitmap, finders [3]finderPattern, moduleSize float64) uint {
+	topLeft, topRight, bottomLeft := finders[0], finders[1], finders[2]
+	dimension := func(moduleSize float64) float64 {
+		return (distance(topLeft, topRight)+distance(topLeft, bottomLeft))/(2*moduleSize) + 7
+	}
+
+	u := [2]float64{(topRight.x - topLeft.x) / distance(topLeft, topRight), (topRight.y - topLeft.y) / distance(topLeft, topRight)}
+	v := [2]float64{(bottomLeft.x - topLeft.x) / distance(topLeft, bottomLeft), (bottomLeft.y - topLeft.y) / distance(topLeft, bottomLeft)}
+	if voted, ok := voteDimension(b, finders[:], u, v, dimension); ok {
+		return uint((voted - 17) / 4)
+	}
+	return versionFromDimension(dimension(moduleSize))
+}
+
+// moduleSizeAlong measures the size of a dot along the line joining the centers of the two given finders,
+// from the width of both finders in this direction (7 dots each).
+// It returns 0 if the finders cannot be measured.
+func moduleSizeAlong(b *bitmap, from, to finderPattern) float64 {
+	dx, dy := (to.x-from.x)/distance(from, to), (to.y-from.y)/distance(from, to)
+	width := finderWidthAlong(b, from, dx, dy) + finderWidthAlong(b, to, dx, dy)
+	if width == 0 {
+		return 0
+	}
+	return width / 14
+}
+
+// finderWidthAlong measures the width of the given finder in direction (dx, dy), in pixels: starting from its center,
+// it walks in both directions through the black center, then the white and black rings, until the next white pixel.
+// It returns 0 if the finder cannot be measured.
+func finderWidthAlong(b *bitmap, finder finderPattern, dx, dy float64) float64 {
+	maxDistance := 7 * finder.moduleSize // finders module sizes are overestimated by up to sqrt(2)
+	width := 0.
+	for _, direction := range []float64{-1, 1} {
+		found := false
+		runs := 0
+		black := true
+		for t := 0.; t < maxDistance; t++ {
+			x, y := finder.x+direction*t*dx, finder.y+direction*t*dy
+			if b.at(int(math.Floor(x)), int(math.Floor(y))) == black {
+				continue
+			}
+			black = !black
+			runs++
+			if runs == 3 { // center, white ring and black ring crossed
+				width += t
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+	return width
+}
+
+// dotSamples are the positions where each dot is sampled, relative to its center and in dots, with their weights:
+// a 3x3 grid spanning half a dot, the center counting twice.
+var dotSamples = [9]struct{ dx, dy, weight float64 }{
+	{-0.25, -0.25, 1}, {0, -0.25, 1}, {0.25, -0.25, 1},
+	{-0.25, 0, 1}, {0, 0, 2}, {0.25, 0, 1},
+	{-0.25, 0.25, 1}, {0, 0.25, 1}, {0.25, 0.25, 1},
+}
+
+// readSoftDot samples the luminance of the dot centered at (x, y), in dots coordinates, using the given transformation
+// from dots coordinates to image coordinates. Each sample is compared with the threshold the binarization used
+// for its pixel: it counts as fully black (or white) when it is at least halfContrast darker (or lighter),
+// and proportionally in-between.
+// The darkness of the dot is the weighted mean of its samples, from 0 to 1, and its confidence tells how far it is
+// from the threshold: a dot read right at the threshold is a tie.
+func readSoftDot(gray *grayImage, b *bitmap, halfContrast float64, toImage func(x, y float64) (float64, float64), x, y float64) SoftDot {
+	darkness, total := 0., 0.
+	for _, sample := range dotSamples {
+		px, py := toImage(x+sample.dx, y+sample.dy)
+		xi, yi := int(math.Floor(px)), int(math.Floor(py))
+		// from -1 (white) to 1 (black)
+		shade := min(max((b.thresholdAt(xi, yi)-gray.at(xi, yi))/halfContrast, -1), 1)
+		darkness += sample.weight * shade
+		total += sample.weight
+	}
+	darkness /= total
+	return SoftDot{Darkness: (1 + darkness) / 2, Confidence: math.Abs(darkness)}
+}
+
+// sampleSoftDots reads each dot of the QR-code from several samples around its center, using the given transformation
+// from dots coordinates to image coordinates, as readSoftDot does.
+func sampleSoftDots(gray *grayImage, b *bitmap, halfContrast float64, toImage func(x, y float64) (float64, float64), size int) SoftQRCode {
+	dots := make(SoftQRCode, size)
+	for i := range dots {
+		dots[i] = make([]SoftDot, size)
+		for j := range dots[i] {
+			dots[i][j] = readSoftDot(gray, b, halfContrast, toImage, float64(j)+0.5, float64(i)+0.5)
+		}
+	}
+	return dots
+}
+
+// sampleDots reads the color of the center of each dot of the QR-code, using the given transformation
+// from dots coordinates to image coordinates.
+func sampleDots(b *bitmap, transform homography, size int) QRCode {
+	dots := make(QRCode, size)
+	for i := range dots {
+		dots[i] = make([]bool, size)
+		for j := range dots[i] {
+			x, y := transform.apply(float64(j)+0.5, float64(i)+0.5)
+			dots[i][j] = b.at(int(math.Floor(x)), int(math.Floor(y)))
+		}
+	}
+	return dots
+}
@@ -0,0 +1,22 @@
+package detect
+
+// Options configure QR-code detection.
+type Options struct {
+	// MaxAngleDeviation is the maximum difference between each angle of the QR-code outline and a right angle,
+	// in degrees. It limits the perspective distortion accepted (0 means squares only).
+	MaxAngleDeviation float64
+	// MaxSideRatio is the maximum ratio between the longest and the shortest sides of the QR-code outline.
+	MaxSideRatio float64
+	// Binarization is the method used to tell black pixels from white ones.
+	Binarization Binarization
+	// Version is the version to sample the QR-code with, or 0 to deduce it from its finder patterns
+	// and version information.
+	Version uint
+}
+
+// DefaultOptions accept codes tilted up to about 45 degrees towards the camera.
+var DefaultOptions = Options{
+	MaxAngleDeviation: 40,
+	MaxSideRatio:      2,
+	Binarization:      BinarizationOtsu,
+}
@@ -0,0 +1,106 @@
+package detect
+
+// QRCode is the representation of the code: 2-dimensional array of dots
+// "true" means black dot, "false" means
+type QRCode [][]bool
+
+// Transpose returns the QR-code mirrored along its main diagonal: dot (i, j) becomes dot (j, i).
+// This is the way mirror images of QR-codes are read, once their finders have been oriented.
+func (qr QRCode) Transpose() QRCode {
+	if len(qr) == 0 {
+		return QRCode{}
+	}
+	transposed := make(QRCode, len(qr[0]))
+	for j := range transposed {
+		transposed[j] = make([]bool, len(qr))
+		for i := range qr {
+			transposed[j][i] = qr[i][j]
+		}
+	}
+	return transposed
+}
+
+// Invert returns the QR-code with black and white dots swapped.
+// This is the way light-on-dark QR-codes (e.g. displayed in dark mode) are read.
+func (qr QRCode) Invert() QRCode {
+	inverted := make(QRCode, len(qr))
+	for i := range qr {
+		inverted[i] = make([]bool, len(qr[i]))
+		for j, dot := range qr[i] {
+			inverted[i][j] = !dot
+		}
+	}
+	return inverted
+}
+
+// SoftQRCode is the representation of the code as read from an image: 2-dimensional array of dots,
+// each of them with the confidence of its reading.
+type SoftQRCode [][]SoftDot
+
+// SoftDot is a dot read from several samples of the image.
+type SoftDot struct {
+	// Darkness is the weighted mean shade of the samples in the dot, from 0 (white) to 1 (black), 0.5 being
+	// the binarization threshold.
+	Darkness float64
+	// Confidence tells how far the dot is from the threshold, from 0 (right at the threshold, or as many black
+	// samples as white ones) to 1 (all samples clearly black, or clearly white).
+	Confidence float64
+}
+
+// Black returns whether the dot is more likely black than white.
+func (dot SoftDot) Black() bool {
+	return dot.Darkness >= 0.5
+}
+
+// Dots returns the most likely color of each dot.
+func (qr SoftQRCode) Dots() QRCode {
+	dots := make(QRCode, len(qr))
+	for i := range qr {
+		dots[i] = make([]bool, len(qr[i]))
+		for j, dot := range qr[i] {
+			dots[i][j] = dot.Black()
+		}
+	}
+	return dots
+}
+
+// Doubtful returns the number of dots read with a confidence lower than the given one.
+func (qr SoftQRCode) Doubtful(minConfidence float64) int {
+	doubtful := 0
+	for i := range qr {
+		for _, dot := range qr[i] {
+			if dot.Confidence < minConfidence {
+				doubtful++
+			}
+		}
+	}
+	return doubtful
+}
+
+// Transpose returns the QR-code mirrored along its main diagonal, as QRCode.Transpose does.
+func (qr SoftQRCode) Transpose() SoftQRCode {
+	if len(qr) == 0 {
+		return SoftQRCode{}
+	}
+	transposed := make(SoftQRCode, len(qr[0]))
+	for j := range transposed {
+		transposed[j] = make([]SoftDot, len(qr))
+		for i := range qr {
+			transposed[j][i] = qr[i][j]
+		}
+	}
+	return transposed
+}
+
+// Invert returns the QR-code with black and white dots swapped, as QRCode.Invert does.
+// The confidence of each dot is kept.
+func (qr SoftQRCode) Invert() SoftQRCode {
+	inverted := make(SoftQRCode, len(qr))
+	for i := range qr {
+		inverted[i] = make([]SoftDot, len(qr[i]))
+		for j, dot := range qr[i] {
+			inverted[i][j] = SoftDot{Darkness: 1 - dot.Darkness, Confidence: dot.Confidence}
+		}
+	}
+	return inverted
+}
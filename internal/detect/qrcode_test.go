@@ -0,0 +1,117 @@
+package detect
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestReadSoftDot(t *testing.T) {
+	// 4x4 pixels dots: black, white, gray right at the threshold, then dark gray, with a white pixel sampled
+	// in the first dot
+	gray := &grayImage{width: 16, height: 4, pix: make([]uint8, 16*4)}
+	for y := range 4 {
+		for x := range 16 {
+			gray.pix[y*16+x] = []uint8{0, 255, 128, 98}[x/4]
+		}
+	}
+	gray.pix[1*16+1] = 255
+	b := binarizeGlobal(gray, 128)
+	toImage := func(x, y float64) (float64, float64) { return 4 * x, 4 * y }
+
+	tests := []struct {
+		name               string
+		x, y               float64
+		expectedDarkness   float64
+		expectedConfidence float64
+	}{
+		{
+			name:               "black dot, with a white sample in its corner",
+			x:                  0.5,
+			y:                  0.5,
+			expectedDarkness:   0.9,
+			expectedConfidence: 0.8,
+		},
+		{
+			name:               "white dot",
+			x:                  1.5,
+			y:                  0.5,
+			expectedDarkness:   0,
+			expectedConfidence: 1,
+		},
+		{
+			name:               "dot across the edge",
+			x:                  1,
+			y:                  0.5,
+			expectedDarkness:   0.3,
+			expectedConfidence: 0.4,
+		},
+		{
+			name:               "gray dot at the threshold",
+			x:                  2.5,
+			y:                  0.5,
+			expectedDarkness:   0.5,
+			expectedConfidence: 0,
+		},
+		{
+			name:               "dark gray dot",
+			x:                  3.5,
+			y:                  0.5,
+			expectedDarkness:   0.65,
+			expectedConfidence: 0.3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dot := readSoftDot(gray, b, 100, toImage, test.x, test.y)
+			if math.Abs(dot.Darkness-test.expectedDarkness) > 1e-9 {
+				t.Errorf("expected darkness %f but got %f", test.expectedDarkness, dot.Darkness)
+			}
+			if math.Abs(dot.Confidence-test.expectedConfidence) > 1e-9 {
+				t.Errorf("expected confidence %f but got %f", test.expectedConfidence, dot.Confidence)
+			}
+		})
+	}
+}
+
+func TestSoftQRCode(t *testing.T) {
+	soft := SoftQRCode{
+		{{Darkness: 1, Confidence: 1}, {Darkness: 0.6, Confidence: 0.2}},
+		{{Darkness: 0.3, Confidence: 0.4}, {Darkness: 0, Confidence: 1}},
+	}
+
+	if expected, actual := (QRCode{{true, true}, {false, false}}), soft.Dots(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected dots %v but got %v", expected, actual)
+	}
+	if expected, actual := (QRCode{{true, false}, {true, false}}), soft.Transpose().Dots(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected transposed dots %v but got %v", expected, actual)
+	}
+	if expected, actual := (QRCode{{false, false}, {true, true}}), soft.Invert().Dots(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected inverted dots %v but got %v", expected, actual)
+	}
+	if expected, actual := soft.Dots().Invert(), soft.Invert().Dots(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected inverted soft dots to match inverted dots %v but got %v", expected, actual)
+	}
+	if doubtful := soft.Invert().Doubtful(0.5); doubtful != 2 {
+		t.Errorf("expected 2 doubtful dots once inverted but got %d", doubtful)
+	}
+	if doubtful := soft.Doubtful(0.5); doubtful != 2 {
+		t.Errorf("expected 2 doubtful dots but got %d", doubtful)
+	}
+}
+
+func TestQRCode_Empty(t *testing.T) {
+	if transposed := (QRCode{}).Transpose(); len(transposed) != 0 {
+		t.Errorf("expected empty transposed QR-code but got %v", transposed)
+	}
+	if inverted := QRCode(nil).Invert(); len(inverted) != 0 {
+		t.Errorf("expected empty inverted QR-code but got %v", inverted)
+	}
+	if transposed := SoftQRCode(nil).Transpose(); len(transposed) != 0 {
+		t.Errorf("expected empty transposed soft QR-code but got %v", transposed)
+	}
+	if inverted := (SoftQRCode{}).Invert(); len(inverted) != 0 {
+		t.Errorf("expected empty inverted soft QR-code but got %v", inverted)
+	}
+}
@@ -0,0 +1,79 @@
+package detect
+
+import (
+	"image"
+	"math"
+)
+
+// ValidateQuadrilateral returns whether the 4 given points form a QR-code outline in the image:
+// all points are inside the image, they form a convex quadrilateral (whatever its rotation), and the perspective
+// distortion is within the limits given by the options.
+// Parameters width and height correspond to the global image size, to check for out-of-image points.
+func ValidateQuadrilateral(points []image.Point, width, height int, options Options) bool {
+	if len(points) != 4 {
+		return false
+	}
+
+	for _, point := range points {
+		if point.X < 0 || point.X >= width || point.Y < 0 || point.Y >= height {
+			return false
+		}
+	}
+
+	return validShape(points, options)
+}
+
+// validShape returns whether the 4 given points form a convex quadrilateral, with angles and sides within
+// the limits given by the options.
+func validShape(points []image.Point, options Options) bool {
+	sign := 0.
+	minSide, maxSide := math.Inf(1), 0.
+	for i := range points {
+		previous, current, next := points[(i+3)%4], points[i], points[(i+1)%4]
+		ux, uy := float64(previous.X-current.X), float64(previous.Y-current.Y)
+		vx, vy := float64(next.X-current.X), float64(next.Y-current.Y)
+
+		side := math.Hypot(vx, vy)
+		if side == 0 {
+			return false
+		}
+		minSide, maxSide = math.Min(minSide, side), math.Max(maxSide, side)
+
+		// convex: all corners turn the same way
+		crossProduct := ux*vy - uy*vx
+		if crossProduct == 0 || crossProduct*sign < 0 {
+			return false
+		}
+		sign = crossProduct
+
+		angle := math.Acos((ux*vx+uy*vy)/(math.Hypot(ux, uy)*side)) * 180 / math.Pi
+		if math.Abs(angle-90) > options.MaxAngleDeviation {
+			return false
+		}
+	}
+
+	return maxSide <= options.MaxSideRatio*minSide
+}
+
+// orderClockwise returns the 4 points of a convex quadrilateral so that they are ordered clockwise
+// in the image (y axis pointing downwards), keeping the first point.
+func orderClockwise(points []image.Point) []image.Point {
+	area := 0
+	for i := range points {
+		current, next := points[i], points[(i+1)%len(points)]
+		area += current.X*next.Y - next.X*current.Y
+	}
+	if area >= 0 {
+		return points
+	}
+	return []image.Point{points[0], points[3], points[2], points[1]}
+}
+
+// rotatePoints returns the points, starting from the one at the given index.
+func rotatePoints(points []image.Point, start int) []image.Point {
+	rotated := make([]image.Point, 0, len(points))
+	for i := range points {
+		rotated = append(rotated, points[(start+i)%len(points)])
+	}
+	return rotated
+}
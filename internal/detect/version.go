@@ -0,0 +1,70 @@
+package detect
+
+import "math/bits"
+
+// Version information is decoded again by the extract package, which cannot be used here (it depends on this one):
+// the detector only needs it to choose the QR-code dimension to sample, before dots are extracted.
+// See https://www.thonky.com/qr-code-tutorial/format-version-information#version-information-string
+
+// minVersionWithInformation is the first version for which the version information blocks are present.
+const minVersionWithInformation = 7
+
+// maxVersionErrors is the number of errors which can be corrected in an 18-bits version information string,
+// the minimum Hamming distance between two valid strings being 8.
+const maxVersionErrors = 3
+
+// versionGenerator is the generator polynom of the BCH (18,6) code protecting the version information.
+const versionGenerator uint32 = 0b1111100100101
+
+// readVersion decodes the version information blocks of the sampled dots, located on the left of the top-right finder
+// and above the bottom-left finder, and returns the version of the block with the fewest errors.
+// Both blocks are close to the finders: they can be read even when the dots are sampled with a slightly wrong dimension.
+// It returns false if none of the blocks can be corrected.
+func readVersion(dots QRCode) (uint, bool) {
+	size := len(dots)
+	if size < 17+4*minVersionWithInformation {
+		return 0, false
+	}
+	var topRight, bottomLeft uint32
+	for i := 17; i >= 0; i-- {
+		topRight, bottomLeft = topRight<<1, bottomLeft<<1
+		if dots[i/3][size-11+i%3] {
+			topRight++
+		}
+		if dots[size-11+i%3][i/3] {
+			bottomLeft++
+		}
+	}
+
+	version1, distance1 := decodeVersion(topRight)
+	version2, distance2 := decodeVersion(bottomLeft)
+	if distance2 < distance1 {
+		version1, distance1 = version2, distance2
+	}
+	return version1, distance1 <= maxVersionErrors
+}
+
+// decodeVersion returns the version whose 18-bits version information string is the closest to the given one,
+// along with the Hamming distance between both strings (i.e. the number of errors to correct).
+func decodeVersion(information uint32) (uint, int) {
+	bestVersion, bestDistance := uint(0), 19
+	for version := uint32(minVersionWithInformation); version <= 40; version++ {
+		candidate := version<<12 | versionRemainder(version)
+		if distance := bits.OnesCount32(information ^ candidate); distance < bestDistance {
+			bestVersion, bestDistance = uint(version), distance
+		}
+	}
+	return bestVersion, bestDistance
+}
+
+// versionRemainder computes the 12-bits error correction code of the given 6-bits version,
+// as the remainder of its polynomial division by versionGenerator.
+func versionRemainder(version uint32) uint32 {
+	remainder := version << 12
+	for i := 17; i >= 12; i-- {
+		if remainder&(1<<i) != 0 {
+			remainder ^= versionGenerator << (i - 12)
+		}
+	}
+	return remainder
+}
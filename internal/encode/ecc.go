@@ -0,0 +1,113 @@
+package encode
+
+import (
+	"github.com/benoitmasson/qrcode-demo/internal/decode"
+)
+
+// Inspired from https://www.thonky.com/qr-code-tutorial/error-correction-coding
+// and https://en.m.wikiversity.org/wiki/Reed%E2%80%93Solomon_codes_for_coders#RS_encoding
+
+func init() {
+	initGaloisField()
+}
+
+// primitivePolynomial is the irreducible polynomial used to build the Galois field GF(256),
+// the same as used for decoding (x^8 + x^4 + x^3 + x^2 + 1).
+const primitivePolynomial = 0b100011101 // 285
+
+// gfExp and gfLog are the exponential and logarithm tables in GF(256), with generator 2.
+// gfExp is doubled in size to avoid a modulo when multiplying.
+var (
+	gfExp [512]byte
+	gfLog [256]int
+)
+
+func initGaloisField() {
+	x := 1
+	for i := range 255 {
+		gfExp[i] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x >= 256 {
+			x ^= primitivePolynomial
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMultiply(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+// generatorPolynomial returns the coefficients of the Reed-Solomon generator polynomial
+// (x - 2^0)(x - 2^1)…(x - 2^(n-1)), from the highest degree to the lowest.
+func generatorPolynomial(n int) []byte {
+	generator := []byte{1}
+	for i := range n {
+		next := make([]byte, len(generator)+1)
+		for j, coefficient := range generator {
+			next[j] ^= coefficient
+			next[j+1] ^= gfMultiply(coefficient, gfExp[i])
+		}
+		generator = next
+	}
+	return generator
+}
+
+// eccCodewords computes the n Reed-Solomon error correction codewords of the given data block,
+// i.e. the remainder of the division of the data polynomial (multiplied by x^n) by the generator polynomial.
+func eccCodewords(data []byte, n int) []byte {
+	generator := generatorPolynomial(n)
+
+	remainder := make([]byte, len(data)+n)
+	copy(remainder, data)
+	for i := range data {
+		coefficient := remainder[i]
+		if coefficient == 0 {
+			continue
+		}
+		for j, g := range generator {
+			remainder[i+j] ^= gfMultiply(g, coefficient)
+		}
+	}
+	return remainder[len(data):]
+}
+
+// interleave splits the data codewords into blocks, computes the ECC codewords of each block,
+// and interleaves all the codewords. It is the opposite of decode.Correct.
+// The result is the sequence of contents bits to place in the QR-code.
+// See https://www.thonky.com/qr-code-tutorial/structure-final-message
+func interleave(codewords []byte, version uint, errorCorrectionLevel decode.ErrorCorrectionLevel) []bool {
+	contentBlockBytes, numberECCSymbols := decode.BlockSizes(version, errorCorrectionLevel)
+
+	contentBlocks := make([][]byte, 0, len(contentBlockBytes))
+	eccBlocks := make([][]byte, 0, len(contentBlockBytes))
+	maxContentLength := 0
+	for _, n := range contentBlockBytes {
+		block := codewords[:n]
+		codewords = codewords[n:]
+		contentBlocks = append(contentBlocks, block)
+		eccBlocks = append(eccBlocks, eccCodewords(block, numberECCSymbols))
+		maxContentLength = max(maxContentLength, n)
+	}
+
+	bits := make([]bool, 0, 8*(maxContentLength+numberECCSymbols)*len(contentBlocks))
+	for j := range maxContentLength {
+		for _, block := range contentBlocks {
+			if j < len(block) {
+				bits = appendUint(bits, uint(block[j]), 8)
+			}
+		}
+	}
+	for j := range numberECCSymbols {
+		for _, block := range eccBlocks {
+			bits = appendUint(bits, uint(block[j]), 8)
+		}
+	}
+	return bits
+}
@@ -0,0 +1,228 @@
+package encode
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/benoitmasson/qrcode-demo/internal/decode"
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+)
+
+// Inspired from https://www.thonky.com/qr-code-tutorial/data-encoding
+
+// errorCorrectionLevelsByStrength lists the error correction levels, from the weakest to the strongest.
+var errorCorrectionLevelsByStrength = []decode.ErrorCorrectionLevel{
+	decode.ErrorCorrectionLevelLow,
+	decode.ErrorCorrectionLevelMedium,
+	decode.ErrorCorrectionLevelQuartile,
+	decode.ErrorCorrectionLevelHigh,
+}
+
+// utf8ECI is the ECI assignment number of the UTF-8 character set.
+const utf8ECI = 26
+
+const alphanumericCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// padBytes are appended alternately at the end of the contents, until the data capacity is reached.
+var padBytes = []byte{0b11101100, 0b00010001} // 236, 17
+
+// Encode encodes the given text into a QR-code.
+// The most compact mode able to represent the whole text is selected (numeric, alphanumeric or byte).
+// In byte mode, text with non-ASCII characters is encoded as UTF-8, preceded by the matching ECI designator.
+//
+// The smallest version able to hold the text with at least the given error correction level is selected,
+// then the error correction level is raised as long as the text still fits in this version.
+func Encode(text string, minErrorCorrectionLevel decode.ErrorCorrectionLevel) (detect.QRCode, error) {
+	if !utf8.ValidString(text) {
+		return nil, errors.New("text is not valid UTF-8")
+	}
+
+	mode := selectMode(text)
+	var header []bool
+	if mode == decode.ByteMode && !isASCII(text) {
+		header = eciDesignator(utf8ECI)
+	}
+	return encode(mode, []byte(text), header, minErrorCorrectionLevel)
+}
+
+// EncodeBytes encodes the given raw bytes into a QR-code, in byte mode (without any ECI designator).
+// Version and error correction level are selected as in Encode.
+func EncodeBytes(data []byte, minErrorCorrectionLevel decode.ErrorCorrectionLevel) (detect.QRCode, error) {
+	return encode(decode.ByteMode, data, nil, minErrorCorrectionLevel)
+}
+
+// encode builds the QR-code holding the given data, encoded with the given mode and preceded by the given header bits.
+func encode(mode decode.Mode, data []byte, header []bool, minErrorCorrectionLevel decode.ErrorCorrectionLevel) (detect.QRCode, error) {
+	if len(data) == 0 {
+		return nil, errors.New("no data to encode")
+	}
+	charactersBits := encodeCharacters(mode, data)
+
+	version, errorCorrectionLevel, err := selectVersion(mode, uint(len(data)), len(header)+len(charactersBits), minErrorCorrectionLevel)
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug(fmt.Sprintf("Mode is %s / Version is %d / Error correction level is %s", mode.String(), version, errorCorrectionLevel.String()))
+
+	bits := make([]bool, 0, len(header)+4+decode.LengthBits(version, mode)+len(charactersBits))
+	bits = append(bits, header...)
+	bits = appendUint(bits, uint(mode), 4)
+	bits = appendUint(bits, uint(len(data)), decode.LengthBits(version, mode))
+	bits = append(bits, charactersBits...)
+
+	codewords := dataCodewords(bits, version, errorCorrectionLevel)
+	contents := interleave(codewords, version, errorCorrectionLevel)
+
+	return newMatrix(contents, version, errorCorrectionLevel), nil
+}
+
+// selectMode returns the most compact mode able to represent all the characters of the given text.
+func selectMode(text string) decode.Mode {
+	if strings.Trim(text, "0123456789") == "" {
+		return decode.NumericMode
+	}
+	if strings.Trim(text, alphanumericCharacters) == "" {
+		return decode.AlphanumericMode
+	}
+	return decode.ByteMode
+}
+
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// eciDesignator returns the ECI mode indicator followed by the given assignment number,
+// encoded on 1, 2 or 3 bytes (see decode.GetECI).
+func eciDesignator(assignment uint) []bool {
+	bits := appendUint(nil, uint(decode.ECIMode), 4)
+	switch {
+	case assignment < 1<<7:
+		return appendUint(bits, assignment, 8)
+	case assignment < 1<<14:
+		return appendUint(bits, 0b10<<14|assignment, 16)
+	default:
+		return appendUint(bits, 0b110<<21|assignment, 24)
+	}
+}
+
+// selectVersion returns the smallest version able to hold the given number of characters with the given mode,
+// and the strongest error correction level (no weaker than the given one) which still fits in this version.
+// charactersBits is the number of bits of the encoded characters, including any header before the mode indicator.
+func selectVersion(mode decode.Mode, length uint, charactersBits int, minErrorCorrectionLevel decode.ErrorCorrectionLevel) (uint, decode.ErrorCorrectionLevel, error) {
+	levels := errorCorrectionLevelsByStrength
+	for i, level := range levels {
+		if level == minErrorCorrectionLevel {
+			levels = levels[i:]
+			break
+		}
+	}
+
+	fits := func(version uint, errorCorrectionLevel decode.ErrorCorrectionLevel) bool {
+		if length > decode.Capacity(version, errorCorrectionLevel, mode) {
+			return false
+		}
+		requiredBits := charactersBits + 4 + decode.LengthBits(version, mode)
+		return requiredBits <= 8*dataCapacity(version, errorCorrectionLevel)
+	}
+
+	for version := uint(1); version <= 40; version++ {
+		if !fits(version, levels[0]) {
+			continue
+		}
+		errorCorrectionLevel := levels[0]
+		for _, level := range levels[1:] {
+			if fits(version, level) {
+				errorCorrectionLevel = level
+			}
+		}
+		return version, errorCorrectionLevel, nil
+	}
+	return 0, 0, fmt.Errorf("data too long: %d characters in %s mode do not fit in a QR-code with error correction level %s",
+		length, mode.String(), levels[0].String())
+}
+
+// dataCapacity returns the number of content bytes (without ECC symbols) of a QR-code.
+func dataCapacity(version uint, errorCorrectionLevel decode.ErrorCorrectionLevel) int {
+	contentBlockBytes, _ := decode.BlockSizes(version, errorCorrectionLevel)
+	total := 0
+	for _, n := range contentBlockBytes {
+		total += n
+	}
+	return total
+}
+
+// encodeCharacters converts the data characters into bits, according to the mode.
+// It is the opposite of decode.Message.
+// See https://www.thonky.com/qr-code-tutorial/numeric-mode-encoding
+// and https://www.thonky.com/qr-code-tutorial/alphanumeric-mode-encoding
+func encodeCharacters(mode decode.Mode, data []byte) []bool {
+	bits := make([]bool, 0, 8*len(data))
+
+	switch mode {
+	case decode.NumericMode:
+		// groups of 3 digits are encoded on 10 bits, the remaining 2 or 1 digits on 7 or 4 bits
+		for i := 0; i < len(data); i += 3 {
+			group := data[i:min(i+3, len(data))]
+			value := uint(0)
+			for _, digit := range group {
+				value = 10*value + uint(digit-'0')
+			}
+			bits = appendUint(bits, value, 3*len(group)+1)
+		}
+	case decode.AlphanumericMode:
+		// pairs of characters are encoded on 11 bits, the remaining character on 6 bits
+		for i := 0; i < len(data); i += 2 {
+			value := uint(strings.IndexByte(alphanumericCharacters, data[i]))
+			if i+1 == len(data) {
+				bits = appendUint(bits, value, 6)
+				break
+			}
+			value = 45*value + uint(strings.IndexByte(alphanumericCharacters, data[i+1]))
+			bits = appendUint(bits, value, 11)
+		}
+	default:
+		for _, b := range data {
+			bits = appendUint(bits, uint(b), 8)
+		}
+	}
+
+	return bits
+}
+
+// dataCodewords terminates the given bits and pads them up to the data capacity of the QR-code,
+// then converts them into bytes.
+// See https://www.thonky.com/qr-code-tutorial/data-encoding#step-7-add-terminator-and-pad-bytes
+func dataCodewords(bits []bool, version uint, errorCorrectionLevel decode.ErrorCorrectionLevel) []byte {
+	capacity := dataCapacity(version, errorCorrectionLevel)
+
+	terminatorLength := min(4, 8*capacity-len(bits))
+	bits = appendUint(bits, 0, terminatorLength)
+	if len(bits)%8 != 0 {
+		bits = appendUint(bits, 0, 8-len(bits)%8)
+	}
+
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		codewords = append(codewords, byte(decode.BitsToUint16(bits[i:i+8])))
+	}
+	for i := 0; len(codewords) < capacity; i++ {
+		codewords = append(codewords, padBytes[i%2])
+	}
+	return codewords
+}
+
+// appendUint appends the given value to the bits, on the given number of bits, most significant bit first.
+func appendUint(bits []bool, value uint, length int) []bool {
+	for i := length - 1; i >= 0; i-- {
+		bits = append(bits, value&(1<<i) != 0)
+	}
+	return bits
+}
@@ -0,0 +1,228 @@
+package encode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/benoitmasson/qrcode-demo/internal/decode"
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+	"github.com/benoitmasson/qrcode-demo/internal/extract"
+)
+
+// Example from https://www.thonky.com/qr-code-tutorial/error-correction-coding
+func TestDataCodewords(t *testing.T) {
+	data := []byte("HELLO WORLD")
+	bits := appendUint(nil, uint(decode.AlphanumericMode), 4)
+	bits = appendUint(bits, uint(len(data)), decode.LengthBits(1, decode.AlphanumericMode))
+	bits = append(bits, encodeCharacters(decode.AlphanumericMode, data)...)
+
+	codewords := dataCodewords(bits, 1, decode.ErrorCorrectionLevelQuartile)
+	expectedCodewords := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236}
+	if !bytes.Equal(codewords, expectedCodewords) {
+		t.Errorf("expected data codewords to equal %v but got %v", expectedCodewords, codewords)
+	}
+
+	ecc := eccCodewords(codewords, 13)
+	expectedECC := []byte{168, 72, 22, 82, 217, 54, 156, 0, 46, 15, 180, 122, 16}
+	if !bytes.Equal(ecc, expectedECC) {
+		t.Errorf("expected ECC codewords to equal %v but got %v", expectedECC, ecc)
+	}
+}
+
+func TestEncodeCharacters(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     decode.Mode
+		data     string
+		expected string
+	}{
+		{
+			name:     "numeric",
+			mode:     decode.NumericMode,
+			data:     "8675309",
+			expected: "1101100011" + "1000010010" + "1001",
+		},
+		{
+			name:     "alphanumeric",
+			mode:     decode.AlphanumericMode,
+			data:     "HELLO",
+			expected: "01100001011" + "01111000110" + "011000",
+		},
+		{
+			name:     "byte",
+			mode:     decode.ByteMode,
+			data:     "Hé",
+			expected: "01001000" + "11000011" + "10101001",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bits := encodeCharacters(test.mode, []byte(test.data))
+			if actual := bitsToString(bits); actual != test.expected {
+				t.Errorf("expected bits to equal %s but got %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name                         string
+		text                         string
+		minErrorCorrectionLevel      decode.ErrorCorrectionLevel
+		expectedVersion              uint
+		expectedErrorCorrectionLevel decode.ErrorCorrectionLevel
+		expectedMode                 decode.Mode
+	}{
+		{
+			name:                         "numeric",
+			text:                         "01234567",
+			minErrorCorrectionLevel:      decode.ErrorCorrectionLevelMedium,
+			expectedVersion:              1,
+			expectedErrorCorrectionLevel: decode.ErrorCorrectionLevelHigh,
+			expectedMode:                 decode.NumericMode,
+		},
+		{
+			name:                         "alphanumeric",
+			text:                         "HELLO WORLD",
+			minErrorCorrectionLevel:      decode.ErrorCorrectionLevelQuartile,
+			expectedVersion:              1,
+			expectedErrorCorrectionLevel: decode.ErrorCorrectionLevelQuartile,
+			expectedMode:                 decode.AlphanumericMode,
+		},
+		{
+			name:                         "byte",
+			text:                         "https://github.com/benoitmasson/qrcode-demo",
+			minErrorCorrectionLevel:      decode.ErrorCorrectionLevelLow,
+			expectedVersion:              3,
+			expectedErrorCorrectionLevel: decode.ErrorCorrectionLevelLow,
+			expectedMode:                 decode.ByteMode,
+		},
+		{
+			name:                         "UTF-8 with ECI",
+			text:                         "Grüße, 世界!",
+			minErrorCorrectionLevel:      decode.ErrorCorrectionLevelMedium,
+			expectedVersion:              2,
+			expectedErrorCorrectionLevel: decode.ErrorCorrectionLevelQuartile,
+			expectedMode:                 decode.ByteMode,
+		},
+		{
+			name:                         "version information",
+			text:                         strings.Repeat("QR-code demo ", 20),
+			minErrorCorrectionLevel:      decode.ErrorCorrectionLevelMedium,
+			expectedVersion:              12,
+			expectedErrorCorrectionLevel: decode.ErrorCorrectionLevelMedium,
+			expectedMode:                 decode.ByteMode,
+		},
+		{
+			name:                         "largest version",
+			text:                         strings.Repeat("0123456789", 300),
+			minErrorCorrectionLevel:      decode.ErrorCorrectionLevelHigh,
+			expectedVersion:              40,
+			expectedErrorCorrectionLevel: decode.ErrorCorrectionLevelHigh,
+			expectedMode:                 decode.NumericMode,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dots, err := Encode(test.text, test.minErrorCorrectionLevel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			version, errorCorrectionLevel, segments, text := decodeMatrix(t, dots)
+			if version != test.expectedVersion {
+				t.Errorf("expected version to equal %d but got %d", test.expectedVersion, version)
+			}
+			if errorCorrectionLevel != test.expectedErrorCorrectionLevel {
+				t.Errorf("expected error correction level to equal %s but got %s", test.expectedErrorCorrectionLevel, errorCorrectionLevel)
+			}
+			if mode := segments[0].Mode; mode != test.expectedMode {
+				t.Errorf("expected mode to equal %s but got %s", test.expectedMode, mode)
+			}
+			if text != test.text {
+				t.Errorf("expected text to equal %q but got %q", test.text, text)
+			}
+		})
+	}
+}
+
+func TestEncode_TooLong(t *testing.T) {
+	_, err := Encode(strings.Repeat("a", 2000), decode.ErrorCorrectionLevelHigh)
+	if err == nil {
+		t.Errorf("expected error for text too long")
+	}
+}
+
+func TestEncodeBytes(t *testing.T) {
+	data := []byte{0x00, 0xff, 0x10, 0x80, 0x7f}
+	dots, err := EncodeBytes(data, decode.ErrorCorrectionLevelMedium)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, _, segments, _ := decodeMatrix(t, dots)
+	if !bytes.Equal(segments[0].Bytes, data) {
+		t.Errorf("expected bytes to equal %v but got %v", data, segments[0].Bytes)
+	}
+}
+
+func TestPenalty(t *testing.T) {
+	dots := make(detect.QRCode, 21)
+	for i := range dots {
+		dots[i] = make([]bool, 21)
+	}
+
+	// rule 1: 2 * 21 runs of 21 dots, rule 2: 20 * 20 blocks, rule 3: none, rule 4: 0% dark dots
+	expected := 2*21*19 + 20*20*3 + 0 + 100
+	if p := penalty(dots); p != expected {
+		t.Errorf("expected penalty to equal %d but got %d", expected, p)
+	}
+
+	dots[3] = []bool{true, false, true, true, true, false, true, false, false, false, false, true, false, true, false, true, false, true, false, true, false}
+	if p := penaltyFinderLike(dots); p != 40 {
+		t.Errorf("expected finder-like penalty to equal 40 but got %d", p)
+	}
+}
+
+// decodeMatrix reads the QR-code back, using the decoding pipeline.
+func decodeMatrix(t *testing.T, dots detect.QRCode) (uint, decode.ErrorCorrectionLevel, []decode.Segment, string) {
+	t.Helper()
+
+	version, err := extract.Version(dots)
+	if err != nil {
+		t.Fatalf("failed to read version: %v", err)
+	}
+	if size := len(dots); size != int(17+4*version) {
+		t.Fatalf("QR-code size %d does not match version %d", size, version)
+	}
+	maskID, errorCorrectionLevel, err := extract.Format(dots)
+	if err != nil {
+		t.Fatalf("failed to read format: %v", err)
+	}
+
+	bits, err := decode.Correct(extract.ReadBits(dots, maskID), version, errorCorrectionLevel)
+	if err != nil {
+		t.Fatalf("failed to correct contents: %v", err)
+	}
+	text, segments, err := decode.Segments(bits, version, errorCorrectionLevel)
+	if err != nil {
+		t.Fatalf("failed to decode segments: %v", err)
+	}
+	return version, errorCorrectionLevel, segments, text
+}
+
+func bitsToString(bits []bool) string {
+	var builder strings.Builder
+	for _, bit := range bits {
+		if bit {
+			builder.WriteByte('1')
+		} else {
+			builder.WriteByte('0')
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,98 @@
+package encode
+
+import (
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+)
+
+// Inspired from https://www.thonky.com/qr-code-tutorial/data-masking#determining-the-best-mask
+
+// finderLikePatterns is the pattern (dark-light-dark-dark-dark-light-dark followed by 4 light dots)
+// penalized by rule 3, along with its reverse.
+var finderLikePatterns = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty evaluates the given QR-code with the 4 penalty rules: the lower, the easier to scan.
+func penalty(dots detect.QRCode) int {
+	transposed := dots.Transpose()
+	return penaltyRuns(dots) + penaltyRuns(transposed) +
+		penaltyBlocks(dots) +
+		penaltyFinderLike(dots) + penaltyFinderLike(transposed) +
+		penaltyBalance(dots)
+}
+
+// penaltyRuns implements rule 1: each run of 5 or more dots of the same color in a row
+// costs 3, plus 1 for each additional dot.
+func penaltyRuns(dots detect.QRCode) int {
+	p := 0
+	for _, line := range dots {
+		run := 1
+		for j := 1; j <= len(line); j++ {
+			if j < len(line) && line[j] == line[j-1] {
+				run++
+				continue
+			}
+			if run >= 5 {
+				p += run - 2
+			}
+			run = 1
+		}
+	}
+	return p
+}
+
+// penaltyBlocks implements rule 2: each 2x2 block of dots of the same color costs 3
+// (overlapping blocks are counted separately).
+func penaltyBlocks(dots detect.QRCode) int {
+	p := 0
+	for i := 0; i < len(dots)-1; i++ {
+		for j := 0; j < len(dots[i])-1; j++ {
+			color := dots[i][j]
+			if dots[i][j+1] == color && dots[i+1][j] == color && dots[i+1][j+1] == color {
+				p += 3
+			}
+		}
+	}
+	return p
+}
+
+// penaltyFinderLike implements rule 3: each occurrence of a finder-like pattern in a row costs 40.
+func penaltyFinderLike(dots detect.QRCode) int {
+	p := 0
+	for _, line := range dots {
+		for j := 0; j+len(finderLikePatterns[0]) <= len(line); j++ {
+			for _, pattern := range finderLikePatterns {
+				if matches(line[j:j+len(pattern)], pattern[:]) {
+					p += 40
+				}
+			}
+		}
+	}
+	return p
+}
+
+// penaltyBalance implements rule 4: the farther the proportion of dark dots from 50%,
+// the higher the penalty (10 for every 5% step).
+func penaltyBalance(dots detect.QRCode) int {
+	dark, total := 0, 0
+	for _, line := range dots {
+		for _, dot := range line {
+			if dot {
+				dark++
+			}
+		}
+		total += len(line)
+	}
+	percent := dark * 100 / total
+	return abs(percent-50) / 5 * 10
+}
+
+func matches(dots, pattern []bool) bool {
+	for k := range pattern {
+		if dots[k] != pattern[k] {
+			return false
+		}
+	}
+	return true
+}
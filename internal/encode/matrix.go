@@ -0,0 +1,118 @@
+package encode
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/benoitmasson/qrcode-demo/internal/decode"
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+	"github.com/benoitmasson/qrcode-demo/internal/extract"
+)
+
+// Inspired from https://www.thonky.com/qr-code-tutorial/module-placement-matrix
+
+// newMatrix builds the QR-code of the given version holding the given contents bits:
+// function patterns are placed first, then the contents bits are placed with each mask in turn,
+// and the mask with the lowest penalty is kept.
+func newMatrix(contents []bool, version uint, errorCorrectionLevel decode.ErrorCorrectionLevel) detect.QRCode {
+	base := newFunctionPatterns(version)
+
+	var best detect.QRCode
+	bestPenalty := -1
+	for maskID := range extract.MaskID(extract.NumberOfMasks) {
+		dots := copyMatrix(base)
+		extract.WriteBits(dots, contents, maskID)
+		information := extract.FormatInformation(maskID, errorCorrectionLevel)
+		extract.WriteFormatInformation(dots, information, information)
+
+		p := penalty(dots)
+		slog.Debug(fmt.Sprintf("Mask %d: penalty is %d", maskID, p))
+		if bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = dots, p
+		}
+	}
+	return best
+}
+
+// newFunctionPatterns returns an empty QR-code of the given version, with its function patterns only:
+// finder markers, timing patterns, alignment patterns, dark spot and version information.
+// Format information is left blank, since it depends on the mask.
+func newFunctionPatterns(version uint) detect.QRCode {
+	size := int(17 + 4*version)
+	dots := make(detect.QRCode, size)
+	for i := range dots {
+		dots[i] = make([]bool, size)
+	}
+
+	// finder markers, surrounded by white separators
+	setFinder(dots, 0, 0)
+	setFinder(dots, 0, size-7)
+	setFinder(dots, size-7, 0)
+
+	// timing patterns, alternating black and white between markers
+	for k := 8; k < size-8; k++ {
+		dots[6][k] = k%2 == 0
+		dots[k][6] = k%2 == 0
+	}
+
+	// alignment patterns, except those overlapping finder markers
+	positions := detect.AlignmentPatternPositions(version)
+	for _, row := range positions {
+		for _, col := range positions {
+			if (row == positions[0] && col == positions[0]) ||
+				(row == positions[0] && col == positions[len(positions)-1]) ||
+				(row == positions[len(positions)-1] && col == positions[0]) {
+				continue
+			}
+			setAlignment(dots, row, col)
+		}
+	}
+
+	// dark spot, next to the bottom-left marker
+	dots[size-8][8] = true
+
+	// version information blocks, on the left of the top-right marker and above the bottom-left marker
+	if information := extract.VersionInformation(version); information != 0 {
+		for i := range 18 {
+			bit := information&(1<<i) != 0
+			dots[i/3][size-11+i%3] = bit
+			dots[size-11+i%3][i/3] = bit
+		}
+	}
+
+	return dots
+}
+
+// setFinder draws a 7x7 finder marker whose top-left corner is at position (row, col).
+func setFinder(dots detect.QRCode, row, col int) {
+	for i := range 7 {
+		for j := range 7 {
+			ring := max(abs(i-3), abs(j-3))
+			dots[row+i][col+j] = ring != 2
+		}
+	}
+}
+
+// setAlignment draws a 5x5 alignment pattern centered on position (row, col).
+func setAlignment(dots detect.QRCode, row, col int) {
+	for i := -2; i <= 2; i++ {
+		for j := -2; j <= 2; j++ {
+			dots[row+i][col+j] = max(abs(i), abs(j)) != 1
+		}
+	}
+}
+
+func copyMatrix(dots detect.QRCode) detect.QRCode {
+	dotsCopy := make(detect.QRCode, len(dots))
+	for i, line := range dots {
+		dotsCopy[i] = append([]bool(nil), line...)
+	}
+	return dotsCopy
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,40 @@
+package extract
+
+import "github.com/benoitmasson/qrcode-demo/internal/detect"
+
+// isAlignmentPatternDot returns whether dot at position (i, j) belongs to one of the alignment patterns
+// of the given version (5x5 squares around each alignment pattern center).
+func isAlignmentPatternDot(i, j int, version uint) bool {
+	positions := detect.AlignmentPatternPositions(version)
+	if len(positions) == 0 {
+		return false
+	}
+	first, last := positions[0], positions[len(positions)-1]
+
+	for _, row := range positions {
+		if i < row-2 || i > row+2 {
+			continue
+		}
+		for _, col := range positions {
+			if j < col-2 || j > col+2 {
+				continue
+			}
+			if (row == first && col == first) || (row == first && col == last) || (row == last && col == first) {
+				// overlaps a finder marker, no alignment pattern here
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// isVersionInformationDot returns whether dot at position (i, j) belongs to one of the two version information
+// blocks (6x3 and 3x6 rectangles next to the top-right and bottom-left markers), present from version 7.
+func isVersionInformationDot(i, j int, version uint, size int) bool {
+	if version < 7 {
+		return false
+	}
+	return (i <= 5 && j >= size-11 && j <= size-9) || // top-right block
+		(j <= 5 && i >= size-11 && i <= size-9) // bottom-left block
+}
@@ -1,5 +1,9 @@
 package extract
 
+import (
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+)
+
 // ReadBits extracts the contents bits from the QR-code, excluding markers and all special dots,
 // applying the given mask, and putting everything in the right order.
 // See https://www.thonky.com/qr-code-tutorial/module-placement-matrix#step-6-place-the-data-bits
@@ -9,15 +13,57 @@ func ReadBits(dots [][]bool, maskID MaskID) []bool {
 	size := len(dots)
 	output := make([]bool, 0, size*size)
 
+	forEachSignificantDot(size, func(row, col int) {
+		output = append(output, dots[row][col] != mask(row, col))
+	})
+
+	return output
+}
+
+// ReadConfidences returns the confidence of the reading of each contents bit from the QR-code,
+// in the same order as ReadBits.
+func ReadConfidences(dots detect.SoftQRCode) []float64 {
+	output := make([]float64, 0, len(dots)*len(dots))
+
+	forEachSignificantDot(len(dots), func(row, col int) {
+		output = append(output, dots[row][col].Confidence)
+	})
+
+	return output
+}
+
+// WriteBits places the contents bits in the QR-code, in the same order as ReadBits, applying the given mask.
+// Markers and all special dots are left untouched. Significant dots left over once all bits are placed
+// (remainder bits) are set to 0 before masking.
+func WriteBits(dots [][]bool, bits []bool, maskID MaskID) {
+	mask := masks[maskID]
+	i := 0
+
+	forEachSignificantDot(len(dots), func(row, col int) {
+		bit := false
+		if i < len(bits) {
+			bit = bits[i]
+			i++
+		}
+		dots[row][col] = bit != mask(row, col)
+	})
+}
+
+// forEachSignificantDot calls the given function on all the significant dots of a QR-code of the given size,
+// in reading order: starting from the bottom-right, 2 columns at a time from right to left,
+// alternating upwards and downwards.
+func forEachSignificantDot(size int, f func(row, col int)) {
+	version := uint((size - 17) / 4)
+
 	for col := size - 1; col >= 0; col -= 2 {
 		// read from bottom to top
 		for row := size - 1; row >= 0; row-- {
-			if isSignificantDot(row, col, size) {
-				output = append(output, dots[row][col] != mask(row, col))
+			if isSignificantDot(row, col, version, size) {
+				f(row, col)
 			}
 
-			if isSignificantDot(row, col-1, size) {
-				output = append(output, dots[row][col-1] != mask(row, col-1))
+			if isSignificantDot(row, col-1, version, size) {
+				f(row, col-1)
 			}
 		}
 
@@ -29,22 +75,20 @@ func ReadBits(dots [][]bool, maskID MaskID) []bool {
 
 		// read from top to bottom
 		for row := 0; row < size; row++ {
-			if isSignificantDot(row, col, size) {
-				output = append(output, dots[row][col] != mask(row, col))
+			if isSignificantDot(row, col, version, size) {
+				f(row, col)
 			}
 
-			if isSignificantDot(row, col-1, size) {
-				output = append(output, dots[row][col-1] != mask(row, col-1))
+			if isSignificantDot(row, col-1, version, size) {
+				f(row, col-1)
 			}
 		}
 	}
-
-	return output
 }
 
 // isSignificantDot returns whether dot at position (i, j) represents a valid message bit,
-// and not a specific pattern (marker, version, …)
-func isSignificantDot(i, j, size int) bool {
+// and not a specific pattern (marker, alignment, version, …)
+func isSignificantDot(i, j int, version uint, size int) bool {
 	if (i <= 8 && j <= 8) ||
 		(i <= 8 && j >= size-8) ||
 		(i >= size-8 && j <= 8) {
@@ -52,15 +96,16 @@ func isSignificantDot(i, j, size int) bool {
 		return false
 	}
 
-	if i >= size-9 && i <= size-5 &&
-		j >= size-9 && j <= size-5 {
-		// ignore alignment pattern in the bottom-right
-		// works only for a single pattern, i.e. version 6 and below
+	if isAlignmentPatternDot(i, j, version) {
+		return false
+	}
+
+	if isVersionInformationDot(i, j, version, size) {
 		return false
 	}
 
 	if j == 6 || i == 6 {
-		// ignore version information
+		// ignore timing patterns
 		return false
 	}
 
@@ -1,7 +1,10 @@
 package extract
 
 import (
+	"fmt"
 	"testing"
+
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
 )
 
 const (
@@ -85,6 +88,79 @@ func TestReadbits(t *testing.T) {
 	}
 }
 
+func TestReadBits_AllVersions(t *testing.T) {
+	for version := 1; version <= 40; version++ {
+		t.Run(fmt.Sprintf("version %d", version), func(t *testing.T) {
+			size := 17 + 4*version
+			dots := make([][]bool, size)
+			for i := range dots {
+				dots[i] = make([]bool, size)
+			}
+
+			// number of data modules, once all function patterns are removed
+			// See https://www.nayuki.io/page/creating-a-qr-code-step-by-step
+			expectedLength := (16*version+128)*version + 64
+			if version >= 2 {
+				numberOfAlignmentPatterns := version/7 + 2
+				expectedLength -= (25*numberOfAlignmentPatterns-10)*numberOfAlignmentPatterns - 55
+			}
+			if version >= 7 {
+				expectedLength -= 36 // version information blocks
+			}
+
+			bits := ReadBits(dots, MaskID(0))
+			if len(bits) != expectedLength {
+				t.Errorf("expected %d bits but got %d", expectedLength, len(bits))
+			}
+		})
+	}
+}
+
+func TestWriteBits(t *testing.T) {
+	bits := ReadBits(sampleDots, MaskID(3))
+
+	dots := make([][]bool, len(sampleDots))
+	for i := range dots {
+		dots[i] = append([]bool(nil), sampleDots[i]...)
+	}
+	WriteBits(dots, bits, MaskID(3))
+
+	for i := range dots {
+		compare := compareSlices(dots[i], sampleDots[i])
+		if compare >= 0 {
+			t.Errorf("dots mismatch at position (%d, %d)", i, compare)
+		}
+	}
+}
+
+func TestReadConfidences(t *testing.T) {
+	size := len(sampleDots)
+	soft := make(detect.SoftQRCode, size)
+	for i := range soft {
+		soft[i] = make([]detect.SoftDot, size)
+		for j := range soft[i] {
+			soft[i][j] = detect.SoftDot{Confidence: 1}
+		}
+	}
+	// first two bits read, and a dot of the timing pattern
+	soft[size-1][size-1].Confidence = 0.2
+	soft[size-1][size-2].Confidence = 0.3
+	soft[6][10].Confidence = 0
+
+	confidences := ReadConfidences(soft)
+	if expectedLength := len(ReadBits(sampleDots, MaskID(0))); len(confidences) != expectedLength {
+		t.Fatalf("expected %d confidences but got %d", expectedLength, len(confidences))
+	}
+	if confidences[0] != 0.2 || confidences[1] != 0.3 {
+		t.Errorf("expected first confidences to be [0.2 0.3] but got %v", confidences[:2])
+	}
+	for i, confidence := range confidences[2:] {
+		if confidence != 1 {
+			t.Errorf("expected confidence 1 at position %d but got %f", i+2, confidence)
+		}
+	}
+}
+
 func compareSlices[T comparable](a, b []T) int {
 	if len(a) != len(b) {
 		if len(a) < len(b) {
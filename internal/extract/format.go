@@ -1,9 +1,11 @@
 package extract
 
 import (
-	"errors"
+	"cmp"
 	"fmt"
 	"log/slog"
+	"math/bits"
+	"slices"
 
 	"github.com/benoitmasson/qrcode-demo/internal/decode"
 	"github.com/benoitmasson/qrcode-demo/internal/detect"
@@ -13,77 +15,130 @@ import (
 
 const formatMask = 0b101010000010010 // 21522
 
+// MaxFormatErrors is the number of errors the error correction code of the format information can correct.
+const MaxFormatErrors = 3
+
+// FormatCandidate is a possible format of the QR-code, with its distance to the format information read.
+type FormatCandidate struct {
+	MaskID               MaskID
+	ErrorCorrectionLevel decode.ErrorCorrectionLevel
+	// Distances are the Hamming distances (number of differing bits) between the format information
+	// of the candidate and both copies read from the QR-code: around the top-left marker first,
+	// then split between the two other markers.
+	Distances [2]int
+}
+
+// Distance returns the Hamming distance between the format information of the candidate
+// and the closest copy read from the QR-code.
+func (c FormatCandidate) Distance() int {
+	return min(c.Distances[0], c.Distances[1])
+}
+
+// Formats compares both occurrences of the format information with the 32 valid ones (5-bits format followed
+// by its 10-bits error correction code), and returns all the formats, the more likely first:
+// they are ranked by distance to the closest occurrence, then by total distance to both occurrences.
+func Formats(dots detect.QRCode) []FormatCandidate {
+	information1 := topLeftFormat(dots)
+	information2 := bottomRightFormat(dots)
+	slog.Debug(fmt.Sprintf("Scanned formats: %015b | %015b", information1, information2))
+
+	candidates := make([]FormatCandidate, 0, 32)
+	for format := uint16(0); format <= 0b11111; format++ {
+		maskID, errorCorrectionLevel := maskIDFromFormat(format), errorCorrectionLevelFromFormat(format)
+		information := FormatInformation(maskID, errorCorrectionLevel)
+		candidates = append(candidates, FormatCandidate{
+			MaskID:               maskID,
+			ErrorCorrectionLevel: errorCorrectionLevel,
+			Distances:            [2]int{bits.OnesCount16(information ^ information1), bits.OnesCount16(information ^ information2)},
+		})
+	}
+	slices.SortStableFunc(candidates, func(a, b FormatCandidate) int {
+		return cmp.Or(
+			cmp.Compare(a.Distance(), b.Distance()),
+			cmp.Compare(a.Distances[0]+a.Distances[1], b.Distances[0]+b.Distances[1]),
+		)
+	})
+
+	return candidates
+}
+
 // Format returns the QR-code "format", i.e. the mask ID used for the data dots
 // and the error correction level.
-// It uses both occurrences of the format and its error correction code, and returns
-// the more likely value among all the encoded values. It fails when the format cannot
-// be clearly recovered from the error correction codes.
+// It returns the more likely format among the ones listed by Formats. It fails when the format cannot
+// be recovered by the error correction code, or when the two more likely formats are as close
+// to the format information read.
 func Format(dots detect.QRCode) (MaskID, decode.ErrorCorrectionLevel, error) {
-	format1 := topLeftFormat(dots)
-	format2 := bottomRightFormat(dots)
-	slog.Debug(fmt.Sprintf("Scanned formats: %015b | %015b", format1, format2))
-
-	possibleFormats := make(map[uint16]int, 10)
-
-	format1 ^= formatMask
-	rawFormat1 := uint16(format1 >> 10) // first 5 bits
-	possibleFormats[rawFormat1]++
-	eccFormat1 := uint16(format1 % (1 << 10)) // last 10 bits
-	for _, format := range decodeFormat(eccFormat1) {
-		possibleFormats[format]++
+	candidates := Formats(dots)
+	best, second := candidates[0], candidates[1]
+	if best.Distance() > MaxFormatErrors {
+		return 0, 0, fmt.Errorf("too many errors in format information (%d at least)", best.Distance())
 	}
-
-	format2 ^= formatMask
-	rawFormat2 := uint16(format2 >> 10) // first 5 bits
-	possibleFormats[rawFormat2]++
-	eccFormat2 := uint16(format2 % (1 << 10)) // last 10 bits
-	for _, format := range decodeFormat(eccFormat2) {
-		possibleFormats[format]++
+	if best.Distances[0]+best.Distances[1] == second.Distances[0]+second.Distances[1] && best.Distance() == second.Distance() {
+		return 0, 0, fmt.Errorf("ambiguous value for format: mask %d / level %s and mask %d / level %s are both %d errors away",
+			best.MaskID, best.ErrorCorrectionLevel, second.MaskID, second.ErrorCorrectionLevel, best.Distance())
 	}
+	slog.Debug(fmt.Sprintf("Selected format: mask %d / level %s (%v errors)", best.MaskID, best.ErrorCorrectionLevel, best.Distances))
 
-	format, err := findMoreFrequent(possibleFormats)
-	if err != nil {
-		return 0, 0, err
-	}
-	slog.Debug(fmt.Sprintf("Selected format: %05b", format))
+	return best.MaskID, best.ErrorCorrectionLevel, nil
+}
+
+// FormatInformation returns the 15-bits format string to write in the QR-code for the given mask ID
+// and error correction level: the 5-bits format followed by its 10-bits error correction code,
+// XOR-ed with the format mask.
+func FormatInformation(maskID MaskID, errorCorrectionLevel decode.ErrorCorrectionLevel) uint16 {
+	format := uint16(errorCorrectionLevel)<<3 | uint16(maskID)
+	return (format<<10 | formatRemainders[format]) ^ formatMask
+}
+
+// topLeftFormatPositions are the positions (row, column) of the format information bits around the top-left marker,
+// most significant bit first: along the 9th row, then up the 9th column, skipping timing patterns.
+var topLeftFormatPositions = [15][2]int{
+	{8, 0}, {8, 1}, {8, 2}, {8, 3}, {8, 4}, {8, 5}, {8, 7}, {8, 8},
+	{7, 8}, {5, 8}, {4, 8}, {3, 8}, {2, 8}, {1, 8}, {0, 8},
+}
 
-	return maskIDFromFormat(format), errorCorrectionLevelFromFormat(format), nil
+// otherFormatPositions returns the positions (row, column) of the second copy of the format information bits
+// in a QR-code of the given size, most significant bit first: up the 9th column next to the bottom-left marker,
+// then along the 9th row next to the top-right marker.
+func otherFormatPositions(size int) [15][2]int {
+	var positions [15][2]int
+	for i := range 7 {
+		positions[i] = [2]int{size - 1 - i, 8}
+	}
+	for i := range 8 {
+		positions[7+i] = [2]int{8, size - 8 + i}
+	}
+	return positions
 }
 
 func topLeftFormat(dots detect.QRCode) uint16 {
-	bits := dots[8][0:6]
-	bits = append(bits, dots[8][7:9]...)
-	bits = append(bits, dots[7][8], dots[5][8], dots[4][8], dots[3][8], dots[2][8], dots[1][8], dots[0][8])
-	return decode.BitsToUint16(bits)
+	return readFormat(dots, topLeftFormatPositions)
 }
 
 func bottomRightFormat(dots detect.QRCode) uint16 {
-	l := len(dots)
-	bits := []bool{dots[l-1][8], dots[l-2][8], dots[l-3][8], dots[l-4][8], dots[l-5][8], dots[l-6][8], dots[l-7][8]}
-	bits = append(bits, dots[8][l-8:]...)
-	return decode.BitsToUint16(bits)
+	return readFormat(dots, otherFormatPositions(len(dots)))
 }
 
-func findMoreFrequent(m map[uint16]int) (uint16, error) {
-	bestValues := make([]uint16, 0, len(m))
-	bestCount := 0
-	for val, count := range m {
-		if count == bestCount {
-			bestValues = append(bestValues, val)
-			continue
-		}
-		if count > bestCount {
-			bestCount = count
-			bestValues = make([]uint16, 0, len(m))
-			bestValues = append(bestValues, val)
-			continue
-		}
+func readFormat(dots detect.QRCode, positions [15][2]int) uint16 {
+	bits := make([]bool, 0, len(positions))
+	for _, position := range positions {
+		bits = append(bits, dots[position[0]][position[1]])
 	}
+	return decode.BitsToUint16(bits)
+}
+
+// WriteFormatInformation writes both copies of the 15-bits format information string into the QR-code,
+// most significant bit first, in the same order as they are read by Formats: around the top-left marker first,
+// then split between the two other markers. Both copies are normally the same.
+func WriteFormatInformation(dots detect.QRCode, topLeft, other uint16) {
+	writeFormat(dots, topLeftFormatPositions, topLeft)
+	writeFormat(dots, otherFormatPositions(len(dots)), other)
+}
 
-	if len(bestValues) > 1 {
-		return 0, errors.New("ambiguous value for format")
+func writeFormat(dots detect.QRCode, positions [15][2]int, information uint16) {
+	for i, position := range positions {
+		dots[position[0]][position[1]] = information&(1<<(14-i)) != 0
 	}
-	return bestValues[0], nil
 }
 
 func errorCorrectionLevelFromFormat(format uint16) decode.ErrorCorrectionLevel {
@@ -36,21 +36,3 @@ func computeFormatRemainder(n uint16) uint16 {
 	}
 	return uint16(val)
 }
-
-// decodeFormat returns the 5-bits formats which fit the better with the given 10-bits ECC code.
-func decodeFormat(eccFormat uint16) []uint16 {
-	formatsByHammingDistance := make([][]uint16, 11)
-	for format := uint16(0); format <= 0b11111; format++ {
-		r := formatRemainders[format]
-		hammingDistance := uint16(bits.OnesCount16(eccFormat ^ r)) // number of differences between the given eccFormat and the candidate r
-		formatsByHammingDistance[hammingDistance] = append(formatsByHammingDistance[hammingDistance], format)
-	}
-
-	for _, formats := range formatsByHammingDistance {
-		if len(formats) == 0 {
-			continue
-		}
-		return formats
-	}
-	return nil
-}
@@ -1,9 +1,11 @@
 package extract
 
 import (
+	"math/bits"
 	"testing"
 
 	"github.com/benoitmasson/qrcode-demo/internal/decode"
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
 )
 
 func TestFormat(t *testing.T) {
@@ -19,3 +21,112 @@ func TestFormat(t *testing.T) {
 		t.Errorf("expected errorLevel to equal %s (%d) but got %s (%d)", decode.ErrorCorrectionLevelMedium, decode.ErrorCorrectionLevelMedium, errorLevel, errorLevel)
 	}
 }
+
+// Expected values from https://www.thonky.com/qr-code-tutorial/format-version-tables
+func TestFormatInformation(t *testing.T) {
+	information := FormatInformation(MaskID(0), decode.ErrorCorrectionLevelLow)
+	if information != 0b111011111000100 {
+		t.Errorf("expected format information to equal %015b but got %015b", 0b111011111000100, information)
+	}
+
+	information = FormatInformation(MaskID(7), decode.ErrorCorrectionLevelHigh)
+	if information != 0b000100000111011 {
+		t.Errorf("expected format information to equal %015b but got %015b", 0b000100000111011, information)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	mask3Medium := FormatInformation(MaskID(3), decode.ErrorCorrectionLevelMedium)
+	mask5High := FormatInformation(MaskID(5), decode.ErrorCorrectionLevelHigh)
+
+	type test struct {
+		name              string
+		topLeft           uint16
+		other             uint16
+		expectedMaskID    MaskID
+		expectedLevel     decode.ErrorCorrectionLevel
+		expectedDistances [2]int
+		expectedError     bool
+	}
+	tests := []test{
+		{
+			name:              "both copies valid",
+			topLeft:           mask3Medium,
+			other:             mask3Medium,
+			expectedMaskID:    3,
+			expectedLevel:     decode.ErrorCorrectionLevelMedium,
+			expectedDistances: [2]int{0, 0},
+		},
+		{
+			name:              "errors in both copies",
+			topLeft:           mask3Medium ^ 0b100000000000001,
+			other:             mask3Medium ^ 0b000001000100010,
+			expectedMaskID:    3,
+			expectedLevel:     decode.ErrorCorrectionLevelMedium,
+			expectedDistances: [2]int{2, 3},
+		},
+		{
+			name:              "one copy destroyed",
+			topLeft:           0b111111111111111,
+			other:             mask5High ^ 0b000000000000100,
+			expectedMaskID:    5,
+			expectedLevel:     decode.ErrorCorrectionLevelHigh,
+			expectedDistances: [2]int{bits.OnesCount16(0b111111111111111 ^ mask5High), 1},
+		},
+		{
+			name:          "copies with different values, as many errors",
+			topLeft:       mask3Medium ^ 0b000000000000001,
+			other:         mask5High ^ 0b000000000000001,
+			expectedError: true,
+		},
+		{
+			name:          "too many errors",
+			topLeft:       mask3Medium ^ 0b111100000000000,
+			other:         mask3Medium ^ 0b000000000001111,
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dots := newFormatDots(21, test.topLeft, test.other)
+
+			candidates := Formats(dots)
+			if len(candidates) != 32 {
+				t.Fatalf("expected 32 format candidates but got %d", len(candidates))
+			}
+			for k := 1; k < len(candidates); k++ {
+				if candidates[k].Distance() < candidates[k-1].Distance() {
+					t.Fatalf("expected candidates to be ranked by distance, got %d after %d", candidates[k].Distance(), candidates[k-1].Distance())
+				}
+			}
+
+			maskID, level, err := Format(dots)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got mask %d / level %s", maskID, level)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if maskID != test.expectedMaskID || level != test.expectedLevel {
+				t.Errorf("expected mask %d / level %s but got mask %d / level %s", test.expectedMaskID, test.expectedLevel, maskID, level)
+			}
+			if best := candidates[0]; best.MaskID != maskID || best.ErrorCorrectionLevel != level || best.Distances != test.expectedDistances {
+				t.Errorf("expected best candidate mask %d / level %s with distances %v but got %+v", maskID, level, test.expectedDistances, best)
+			}
+		})
+	}
+}
+
+// newFormatDots returns an empty QR-code of the given size, with only the given format information copies set.
+func newFormatDots(size int, topLeft, other uint16) detect.QRCode {
+	dots := make(detect.QRCode, size)
+	for i := range dots {
+		dots[i] = make([]bool, size)
+	}
+	WriteFormatInformation(dots, topLeft, other)
+	return dots
+}
@@ -4,6 +4,9 @@ package extract
 
 type MaskID uint8
 
+// NumberOfMasks is the number of available masks, hence mask IDs range from 0 to NumberOfMasks-1.
+const NumberOfMasks = 8
+
 // mask is a function which given 2 coordinates i and j, returns whether the dot
 // at these coordinates should be switched (true) or kept as it is (false)
 type mask func(i, j int) bool
@@ -21,3 +24,8 @@ var masks = []mask{
 	/* maskID == 6 */ func(i, j int) bool { return ((i*j)%3+i*j)%2 == 0 },
 	/* maskID == 7 */ func(i, j int) bool { return ((i*j)%3+i+j)%2 == 0 },
 }
+
+// Switches returns whether the mask switches the dot at position (i, j).
+func (maskID MaskID) Switches(i, j int) bool {
+	return masks[maskID](i, j)
+}
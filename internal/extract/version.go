@@ -2,17 +2,58 @@ package extract
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
 
 	"github.com/benoitmasson/qrcode-demo/internal/detect"
 )
 
+// VersionMismatchError is returned when the version information blocks are decoded successfully,
+// but their version does not match the QR-code size: the dots are likely sampled with a wrong dimension,
+// and should be sampled again with the decoded version.
+type VersionMismatchError struct {
+	// Information is the version decoded from the version information blocks.
+	Information uint
+	// Size is the version deduced from the QR-code size.
+	Size uint
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("version information (%d) does not match QR-code size (version %d)", e.Information, e.Size)
+}
+
 // Version gets the QR-code version.
-// In our demo, it is not so useful, but helps detecting false positives.
+// Up to version 6, the version is deduced from the QR-code size, once checked that timing patterns span it.
+// From version 7, both version information blocks are read and decoded using their error correction code,
+// then checked against the QR-code size: a *VersionMismatchError is returned if they disagree.
+// If none of them can be decoded, timing patterns are used as a fallback.
 func Version(dots detect.QRCode) (uint, error) {
 	if !dots[len(dots)-8][8] {
 		return 0, errors.New("dark spot not found")
 	}
 
+	version, err := timingVersion(dots)
+
+	size := len(dots)
+	if (size-17)%4 != 0 || (size-17)/4 < minVersionWithInformation {
+		return version, err
+	}
+	sizeVersion := uint((size - 17) / 4)
+
+	informationVersion, informationErr := versionFromInformation(dots)
+	if informationErr != nil {
+		slog.Debug(fmt.Sprintf("Version information cannot be decoded, fallback to timing patterns: %v", informationErr))
+		return version, err
+	}
+	if informationVersion != sizeVersion {
+		return 0, &VersionMismatchError{Information: informationVersion, Size: sizeVersion}
+	}
+	return informationVersion, nil
+}
+
+// timingVersion checks that the timing patterns alternate from one finder to the other,
+// and deduces the version from their length, i.e. from the QR-code size.
+func timingVersion(dots detect.QRCode) (uint, error) {
 	version, err := verticalVersion(dots)
 	if err != nil {
 		// fallback to horizontal if not found vertically
@@ -22,6 +63,59 @@ func Version(dots detect.QRCode) (uint, error) {
 	return version, nil
 }
 
+// versionFromInformation decodes both version information blocks, and returns the most likely version.
+// It fails when none of the blocks can be corrected, or when both are corrected to different versions
+// with the same number of errors.
+func versionFromInformation(dots detect.QRCode) (uint, error) {
+	information1 := topRightVersion(dots)
+	information2 := bottomLeftVersion(dots)
+	slog.Debug(fmt.Sprintf("Scanned version information: %018b | %018b", information1, information2))
+
+	version1, distance1 := decodeVersion(information1)
+	version2, distance2 := decodeVersion(information2)
+	slog.Debug(fmt.Sprintf("Decoded versions: %d (%d errors) | %d (%d errors)", version1, distance1, version2, distance2))
+
+	switch {
+	case distance1 > maxVersionErrors && distance2 > maxVersionErrors:
+		return 0, errors.New("too many errors in version information blocks")
+	case version1 == version2:
+		return version1, nil
+	case distance1 < distance2:
+		return version1, nil
+	case distance2 < distance1:
+		return version2, nil
+	}
+	return 0, errors.New("ambiguous value for version")
+}
+
+// topRightVersion reads the version information block located on the left of the top-right marker.
+// Bits are read from the least significant one, from top to bottom and left to right.
+func topRightVersion(dots detect.QRCode) uint32 {
+	size := len(dots)
+	information := uint32(0)
+	for i := 17; i >= 0; i-- {
+		information <<= 1
+		if dots[i/3][size-11+i%3] {
+			information++
+		}
+	}
+	return information
+}
+
+// bottomLeftVersion reads the version information block located above the bottom-left marker.
+// It is the transposition of the top-right block.
+func bottomLeftVersion(dots detect.QRCode) uint32 {
+	size := len(dots)
+	information := uint32(0)
+	for i := 17; i >= 0; i-- {
+		information <<= 1
+		if dots[size-11+i%3][i/3] {
+			information++
+		}
+	}
+	return information
+}
+
 // verticalVersion detects alternating dots in the 7-th column, between markers
 func verticalVersion(dots detect.QRCode) (uint, error) {
 	previous := true // black
@@ -0,0 +1,65 @@
+package extract
+
+import (
+	"math/bits"
+)
+
+func init() {
+	initVersionRemainders()
+}
+
+// Inspired from https://www.thonky.com/qr-code-tutorial/format-version-information#version-information-string
+
+const versionGenerator uint32 = 0b1111100100101 // 7973
+
+// minVersionWithInformation is the first version for which the version information blocks are present.
+const minVersionWithInformation = 7
+
+// maxVersionErrors is the number of errors which can be corrected in an 18-bits version information string,
+// the minimum Hamming distance between two valid strings being 8.
+const maxVersionErrors = 3
+
+var versionRemainders = make([]uint32, 41)
+
+func initVersionRemainders() {
+	for version := uint32(minVersionWithInformation); version <= 40; version++ {
+		versionRemainders[version] = computeVersionRemainder(version)
+	}
+}
+
+// computeVersionRemainder is an implementation of the BCH (18,6) code, in the particular
+// case of length-6 strings of 0's and 1's (represented by n), using the versionGenerator polynom.
+func computeVersionRemainder(n uint32) uint32 {
+	mod := uint(versionGenerator) << 5 // pad generator with trailing 0's to have 18 bits => 0b111110010010100000
+	val := uint(n) << 12               // pad version with 12 0's to have 18 bits
+	for i := 0; i < 6; i++ {
+		if bits.Len(val) >= bits.Len(mod) {
+			val ^= mod
+		}
+		mod >>= 1
+	}
+	return uint32(val)
+}
+
+// VersionInformation returns the 18-bits version information string to write in the QR-code for the given version:
+// the 6-bits version followed by its 12-bits error correction code. Versions below 7 have no version information.
+func VersionInformation(version uint) uint32 {
+	if version < minVersionWithInformation || version > 40 {
+		return 0
+	}
+	return uint32(version)<<12 | versionRemainders[version]
+}
+
+// decodeVersion returns the version whose 18-bits version information string is the closest to the given one,
+// along with the Hamming distance between both strings (i.e. the number of errors to correct).
+func decodeVersion(versionInformation uint32) (uint, int) {
+	bestVersion, bestDistance := uint(0), 19
+	for version := uint32(minVersionWithInformation); version <= 40; version++ {
+		candidate := version<<12 | versionRemainders[version]
+		hammingDistance := bits.OnesCount32(versionInformation ^ candidate) // number of differences between the given string and the candidate
+		if hammingDistance < bestDistance {
+			bestVersion, bestDistance = uint(version), hammingDistance
+		}
+	}
+	return bestVersion, bestDistance
+}
@@ -0,0 +1,79 @@
+package extract
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestComputeVersionRemainder(t *testing.T) {
+	type test struct {
+		name              string
+		version           uint32
+		expectedRemainder uint32
+	}
+	tests := []test{
+		{
+			name:              "version 7",
+			version:           7,
+			expectedRemainder: 0b110010010100,
+		},
+		{
+			name:              "version 40",
+			version:           40,
+			expectedRemainder: 0b110001101001,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualRemainder := computeVersionRemainder(test.version)
+			if actualRemainder != test.expectedRemainder {
+				t.Errorf("expected %012s but got %012s", strconv.FormatInt(int64(test.expectedRemainder), 2), strconv.FormatInt(int64(actualRemainder), 2))
+			}
+		})
+	}
+}
+
+func TestDecodeVersion(t *testing.T) {
+	type test struct {
+		name             string
+		information      uint32
+		expectedVersion  uint
+		expectedDistance int
+	}
+	tests := []test{
+		{
+			name:             "no error",
+			information:      0b000111110010010100,
+			expectedVersion:  7,
+			expectedDistance: 0,
+		},
+		{
+			name:             "3 errors",
+			information:      0b100111110011010101,
+			expectedVersion:  7,
+			expectedDistance: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualVersion, actualDistance := decodeVersion(test.information)
+			if actualVersion != test.expectedVersion {
+				t.Errorf("expected version %d but got %d", test.expectedVersion, actualVersion)
+			}
+			if actualDistance != test.expectedDistance {
+				t.Errorf("expected distance %d but got %d", test.expectedDistance, actualDistance)
+			}
+		})
+	}
+}
+
+func TestVersionInformation(t *testing.T) {
+	if information := VersionInformation(7); information != 0b000111110010010100 {
+		t.Errorf("expected version information to equal %018b but got %018b", 0b000111110010010100, information)
+	}
+	if information := VersionInformation(6); information != 0 {
+		t.Errorf("expected no version information for version 6 but got %018b", information)
+	}
+}
@@ -1,7 +1,10 @@
 package extract
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
 )
 
 func TestVersion(t *testing.T) {
@@ -14,3 +17,121 @@ func TestVersion(t *testing.T) {
 		t.Errorf("expected version to equal 2 but got %d", version)
 	}
 }
+
+func TestVersion_Information(t *testing.T) {
+	const (
+		version7 = 0b000111110010010100
+		version8 = 0b001000010110111100
+	)
+
+	type test struct {
+		name            string
+		size            int
+		topRight        uint32
+		bottomLeft      uint32
+		noTiming        bool
+		expectedVersion uint
+		expectedError   bool
+		// expectedInformation is the version expected in a *VersionMismatchError, if any.
+		expectedInformation uint
+	}
+	tests := []test{
+		{
+			name:            "both blocks valid",
+			size:            45,
+			topRight:        version7,
+			bottomLeft:      version7,
+			expectedVersion: 7,
+		},
+		{
+			name:            "one block with errors",
+			size:            45,
+			topRight:        version7 ^ 0b100000001000000001,
+			bottomLeft:      version7,
+			expectedVersion: 7,
+		},
+		{
+			name:            "one block destroyed",
+			size:            45,
+			topRight:        version7,
+			bottomLeft:      0b111111111111111111,
+			expectedVersion: 7,
+		},
+		{
+			name:            "noisy timing patterns",
+			size:            45,
+			topRight:        version7,
+			bottomLeft:      version7,
+			noTiming:        true,
+			expectedVersion: 7,
+		},
+		{
+			name:            "both blocks destroyed, fallback to timing patterns",
+			size:            45,
+			topRight:        0b111111111111111111,
+			bottomLeft:      0b000000000000000000,
+			expectedVersion: 7,
+		},
+		{
+			name:                "version information does not match size",
+			size:                45,
+			topRight:            version8,
+			bottomLeft:          version8,
+			expectedError:       true,
+			expectedInformation: 8,
+		},
+		{
+			name:                "version information does not match size, noisy timing patterns",
+			size:                45,
+			topRight:            version8,
+			bottomLeft:          version8,
+			noTiming:            true,
+			expectedError:       true,
+			expectedInformation: 8,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dots := newVersionDots(test.size, test.topRight, test.bottomLeft, !test.noTiming)
+			actualVersion, err := Version(dots)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got version %d", actualVersion)
+					return
+				}
+				var mismatch *VersionMismatchError
+				if test.expectedInformation != 0 && (!errors.As(err, &mismatch) || mismatch.Information != test.expectedInformation) {
+					t.Errorf("expected version information %d to be reported but got %v", test.expectedInformation, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if actualVersion != test.expectedVersion {
+				t.Errorf("expected version to equal %d but got %d", test.expectedVersion, actualVersion)
+			}
+		})
+	}
+}
+
+// newVersionDots returns an empty QR-code of the given size, with only the dark spot, the timing patterns
+// (if requested) and the given version information blocks set.
+func newVersionDots(size int, topRight, bottomLeft uint32, timing bool) detect.QRCode {
+	dots := make(detect.QRCode, size)
+	for i := range dots {
+		dots[i] = make([]bool, size)
+	}
+	dots[size-8][8] = true
+	for k := 0; k < size; k++ {
+		dots[k][6] = timing && k%2 == 0
+		dots[6][k] = timing && k%2 == 0
+	}
+	for i := 0; i < 18; i++ {
+		dots[i/3][size-11+i%3] = topRight&(1<<i) != 0
+		dots[size-11+i%3][i/3] = bottomLeft&(1<<i) != 0
+	}
+	return dots
+}
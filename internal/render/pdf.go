@@ -0,0 +1,67 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+)
+
+// Inspired from https://opensource.adobe.com/dc-acrobat-sdk-docs/pdfstandards/PDF32000_2008.pdf (sections 7.5 and 8.5)
+
+// PDF writes the QR-code as a single-page PDF document, the page having the size of the code (quiet zone included).
+// Each dot is a square of ModuleSize points (1/72 inch), and dark dots of a row are merged into rectangles.
+func PDF(w io.Writer, dots detect.QRCode, options Options) error {
+	if len(dots) == 0 {
+		return errors.New("empty QR-code")
+	}
+	options = options.withDefaults()
+
+	size := (len(dots) + 2*options.QuietZone) * options.ModuleSize
+
+	// page contents: background, then dark runs (PDF origin is the bottom-left corner)
+	var contents bytes.Buffer
+	fmt.Fprintf(&contents, "%s rg\n0 0 %d %d re f\n", pdfColor(options.Background), size, size)
+	fmt.Fprintf(&contents, "%s rg\n", pdfColor(options.Foreground))
+	for _, r := range darkRuns(dots) {
+		x := (r.col + options.QuietZone) * options.ModuleSize
+		y := size - (r.row+options.QuietZone+1)*options.ModuleSize
+		fmt.Fprintf(&contents, "%d %d %d %d re\n", x, y, r.length*options.ModuleSize, options.ModuleSize)
+	}
+	contents.WriteString("f\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Contents 4 0 R /Resources << >> >>", size, size),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", contents.Len(), contents.String()),
+	}
+
+	// objects are numbered from 1, the cross-reference table gives the byte offset of each one of them
+	var document bytes.Buffer
+	document.WriteString("%PDF-1.4\n")
+	offsets := make([]int, 0, len(objects))
+	for i, object := range objects {
+		offsets = append(offsets, document.Len())
+		fmt.Fprintf(&document, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := document.Len()
+	fmt.Fprintf(&document, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&document, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&document, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := document.WriteTo(w)
+	return err
+}
+
+// pdfColor returns the red, green and blue components of the given color, between 0 and 1.
+func pdfColor(c color.Color) string {
+	r, g, b := rgb(c)
+	return fmt.Sprintf("%.3g %.3g %.3g", float64(r)/255, float64(g)/255, float64(b)/255)
+}
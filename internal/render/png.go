@@ -0,0 +1,43 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+)
+
+// PNG writes the QR-code as a PNG image.
+func PNG(w io.Writer, dots detect.QRCode, options Options) error {
+	img, err := Image(dots, options)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// Image draws the QR-code into a 2-colors image, each dot being a square of ModuleSize pixels.
+func Image(dots detect.QRCode, options Options) (*image.Paletted, error) {
+	if len(dots) == 0 {
+		return nil, errors.New("empty QR-code")
+	}
+	options = options.withDefaults()
+
+	size := (len(dots) + 2*options.QuietZone) * options.ModuleSize
+	palette := color.Palette{options.Background, options.Foreground}
+	img := image.NewPaletted(image.Rect(0, 0, size, size), palette) // filled with background (index 0)
+
+	for _, r := range darkRuns(dots) {
+		y0 := (r.row + options.QuietZone) * options.ModuleSize
+		x0 := (r.col + options.QuietZone) * options.ModuleSize
+		for y := y0; y < y0+options.ModuleSize; y++ {
+			for x := x0; x < x0+r.length*options.ModuleSize; x++ {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img, nil
+}
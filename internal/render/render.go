@@ -0,0 +1,78 @@
+package render
+
+import (
+	"image/color"
+
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+)
+
+// Options configure the rendering of a QR-code.
+type Options struct {
+	// ModuleSize is the size of a dot, in pixels (PNG, SVG) or points (PDF).
+	ModuleSize int
+	// QuietZone is the width of the blank margin around the code, in dots.
+	// The standard requires at least 4 dots. Use a negative width to render the code without margin.
+	QuietZone int
+	// Foreground is the color of the dark dots.
+	Foreground color.Color
+	// Background is the color of the light dots and of the quiet zone.
+	Background color.Color
+}
+
+// DefaultOptions renders black dots of 10 pixels on a white background, with a standard quiet zone.
+var DefaultOptions = Options{
+	ModuleSize: 10,
+	QuietZone:  4,
+	Foreground: color.Black,
+	Background: color.White,
+}
+
+// withDefaults returns the options, with zero values replaced by the default ones.
+// A negative quiet zone means no margin at all.
+func (o Options) withDefaults() Options {
+	if o.ModuleSize <= 0 {
+		o.ModuleSize = DefaultOptions.ModuleSize
+	}
+	switch {
+	case o.QuietZone == 0:
+		o.QuietZone = DefaultOptions.QuietZone
+	case o.QuietZone < 0:
+		o.QuietZone = 0
+	}
+	if o.Foreground == nil {
+		o.Foreground = DefaultOptions.Foreground
+	}
+	if o.Background == nil {
+		o.Background = DefaultOptions.Background
+	}
+	return o
+}
+
+// run is a horizontal sequence of dark dots, in dots coordinates.
+type run struct {
+	row, col, length int
+}
+
+// darkRuns merges the dark dots of each row of the QR-code into horizontal runs, to reduce the number of shapes to draw.
+func darkRuns(dots detect.QRCode) []run {
+	runs := make([]run, 0, len(dots)*4)
+	for i, line := range dots {
+		for j := 0; j < len(line); j++ {
+			if !line[j] {
+				continue
+			}
+			start := j
+			for j < len(line) && line[j] {
+				j++
+			}
+			runs = append(runs, run{row: i, col: start, length: j - start})
+		}
+	}
+	return runs
+}
+
+// rgb returns the 8-bits red, green and blue components of the given color.
+func rgb(c color.Color) (uint8, uint8, uint8) {
+	r, g, b, _ := color.NRGBAModel.Convert(c).RGBA()
+	return uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)
+}
@@ -0,0 +1,145 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+)
+
+const (
+	_0 = false
+	_1 = true
+)
+
+var sampleDots = detect.QRCode{
+	{_1, _1, _0},
+	{_0, _1, _1},
+	{_1, _0, _1},
+}
+
+func TestDarkRuns(t *testing.T) {
+	runs := darkRuns(sampleDots)
+	expected := []run{{0, 0, 2}, {1, 1, 2}, {2, 0, 1}, {2, 2, 1}}
+	if !reflect.DeepEqual(runs, expected) {
+		t.Errorf("expected runs to equal %v but got %v", expected, runs)
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	tests := []struct {
+		name              string
+		options           Options
+		expectedQuietZone int
+	}{
+		{name: "zero value", options: Options{ModuleSize: 4}, expectedQuietZone: 4},
+		{name: "custom quiet zone", options: Options{QuietZone: 2}, expectedQuietZone: 2},
+		{name: "no quiet zone", options: Options{QuietZone: -1}, expectedQuietZone: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options.withDefaults()
+			if options.QuietZone != test.expectedQuietZone {
+				t.Errorf("expected quiet zone %d but got %d", test.expectedQuietZone, options.QuietZone)
+			}
+			if options.ModuleSize <= 0 || options.Foreground == nil || options.Background == nil {
+				t.Errorf("expected all options to be set but got %+v", options)
+			}
+		})
+	}
+}
+
+func TestImage(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	img, err := Image(sampleDots, Options{ModuleSize: 2, QuietZone: 1, Foreground: red})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if img.Bounds().Dx() != 10 || img.Bounds().Dy() != 10 {
+		t.Fatalf("expected a 10x10 image but got %v", img.Bounds())
+	}
+	for y := range 10 {
+		for x := range 10 {
+			i, j := y/2-1, x/2-1
+			expected := color.Color(color.White)
+			if i >= 0 && j >= 0 && i < 3 && j < 3 && sampleDots[i][j] {
+				expected = red
+			}
+			if !sameColor(img.At(x, y), expected) {
+				t.Errorf("expected pixel (%d, %d) to be %v but got %v", x, y, expected, img.At(x, y))
+			}
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	var b bytes.Buffer
+	err := SVG(&b, sampleDots, Options{ModuleSize: 5, QuietZone: 1, Background: color.RGBA{0xff, 0xee, 0xdd, 0xff}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	svg := b.String()
+	for _, expected := range []string{
+		`width="25" height="25" viewBox="0 0 5 5"`,
+		`<rect width="5" height="5" fill="#ffeedd"/>`,
+		`<path d="M1 1h2v1h-2zM2 2h2v1h-2zM1 3h1v1h-1zM3 3h1v1h-1z" fill="#000000"/>`,
+	} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("expected SVG to contain %q, got:\n%s", expected, svg)
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	var b bytes.Buffer
+	err := PDF(&b, sampleDots, Options{ModuleSize: 10, QuietZone: 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pdf := b.String()
+
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Errorf("invalid PDF header or trailer")
+	}
+	if !strings.Contains(pdf, "/MediaBox [0 0 110 110]") {
+		t.Errorf("expected page to be 110 points wide")
+	}
+	if !strings.Contains(pdf, "40 60 20 10 re\n") { // first row, top of the page
+		t.Errorf("expected first run to be drawn at the top of the page")
+	}
+
+	// all offsets in the cross-reference table must point to the matching objects
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(pdf)
+	if startxref == nil {
+		t.Fatalf("startxref not found")
+	}
+	xref, _ := strconv.Atoi(startxref[1])
+	if !strings.HasPrefix(pdf[xref:], "xref\n") {
+		t.Fatalf("startxref does not point to the cross-reference table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllStringSubmatch(pdf[xref:], -1)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 objects but got %d", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if !strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj\n", i+1)) {
+			t.Errorf("offset of object %d does not point to it", i+1)
+		}
+	}
+}
+
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
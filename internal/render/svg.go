@@ -0,0 +1,41 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+)
+
+// SVG writes the QR-code as an SVG image. Coordinates are expressed in dots, and the image is scaled to
+// ModuleSize pixels per dot. Dark dots are drawn as a single path, with adjacent dots of a row merged together.
+func SVG(w io.Writer, dots detect.QRCode, options Options) error {
+	if len(dots) == 0 {
+		return errors.New("empty QR-code")
+	}
+	options = options.withDefaults()
+
+	size := len(dots) + 2*options.QuietZone
+	var path strings.Builder
+	for _, r := range darkRuns(dots) {
+		fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", r.col+options.QuietZone, r.row+options.QuietZone, r.length, r.length)
+	}
+
+	_, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">
+<rect width="%d" height="%d" fill="%s"/>
+<path d="%s" fill="%s"/>
+</svg>
+`, size*options.ModuleSize, size*options.ModuleSize, size, size,
+		size, size, svgColor(options.Background),
+		path.String(), svgColor(options.Foreground))
+	return err
+}
+
+func svgColor(c color.Color) string {
+	r, g, b := rgb(c)
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
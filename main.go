@@ -1,185 +1,104 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"fmt"
 	"image"
-	"image/color"
 	"log/slog"
-	"math"
+	"os"
 
-	"gocv.io/x/gocv"
-
-	"github.com/benoitmasson/qrcode-demo/internal/decode"
-	"github.com/benoitmasson/qrcode-demo/internal/detect"
-	"github.com/benoitmasson/qrcode-demo/internal/extract"
+	"github.com/benoitmasson/qrcode-demo/qrcode"
 )
 
 func main() {
 	// parse args
 	var deviceID int
 	flag.IntVar(&deviceID, "device-id", 0, "Webcam device ID, for image capture")
+	var videoPath string
+	flag.StringVar(&videoPath, "video", "", "Video file to decode (MP4, AVI, MKV, …), instead of the webcam stream")
+	var text, outputPath string
+	flag.StringVar(&text, "encode", "", "Text to encode into a QR-code, instead of decoding")
+	flag.StringVar(&outputPath, "output", "", "File where to export the generated QR-code, or the QR-code decoded from the last image file (PNG, SVG or PDF)")
+	options := qrcode.DefaultOptions
+	flag.Float64Var(&options.MaxAngleDeviation, "max-angle", options.MaxAngleDeviation, "Maximum deviation of the QR-code corners from a right angle, in degrees (perspective distortion)")
+	flag.Float64Var(&options.MaxSideRatio, "max-side-ratio", options.MaxSideRatio, "Maximum ratio between the longest and shortest sides of the QR-code outline")
+	flag.TextVar(&options.Binarization, "binarization", options.Binarization, "Method telling black pixels from white ones: otsu (global threshold), mean or sauvola (local thresholds, for uneven lighting)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [image files...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	webcam, err := gocv.OpenVideoCapture(deviceID)
-	if err != nil {
-		slog.Error(fmt.Sprintf("Error opening video capture device %d: %v", deviceID, err))
+	if text != "" {
+		if err := generateQRCode(text, outputPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to generate QR-code: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
-	defer webcam.Close()
-
-	window := gocv.NewWindow("QR-code decoder")
-	defer window.Close()
-
-	// pre-allocate matrices once and for all
-	img, imgWithMiniCode := gocv.NewMat(), gocv.NewMat()
-	defer img.Close()
-	defer imgWithMiniCode.Close()
-	points := gocv.NewMat()
-	defer points.Close()
-
-	first := true
-	var width, height, fps int
-	slog.Info(fmt.Sprintf("Start reading device: %v", deviceID))
-	for {
-		if ok := webcam.Read(&img); !ok {
-			slog.Error(fmt.Sprintf("Device closed: %v", deviceID))
-			return
+	if videoPath != "" {
+		if err := decodeVideo(videoPath, options); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", videoPath, err)
+			os.Exit(1)
 		}
-		if img.Empty() {
-			continue
-		}
-
-		if first {
-			width = img.Cols()
-			height = img.Rows()
-			fps = int(math.Round(webcam.Get(gocv.VideoCaptureFPS)))
-			slog.Info(fmt.Sprintf("[%s] %dx%d, %dfps", img.Type(), width, height, fps))
-			first = false
-		}
-
-		img, found, message := scanCode(&img, &imgWithMiniCode, &points, width, height)
-
-		window.IMShow(img)
-		if window.WaitKey(1) == 27 {
-			break
-		}
-
-		if found {
-			slog.Warn(fmt.Sprintf("QR-code message is: '\033[1m%s\033[0m'", message))
-			fmt.Println()
-			webcam.Grab(3 * fps) // drop frames and sleep for 3 seconds
+		return
+	}
+	if flag.NArg() > 0 {
+		if !decodeFiles(flag.Args(), outputPath, options) {
+			os.Exit(1)
 		}
+		return
 	}
-}
 
-const (
-	miniCodeWidth  = 200
-	miniCodeHeight = 200
-)
+	scanDevice(deviceID, options)
+}
 
-// scanCode extracts the QR-code from the given image, then decodes it.
-// If successful, returns a new image with miniature QR-code in the top-left corner and the message.
-// Otherwise, returns the original image.
-func scanCode(img, imgWithMiniCode *gocv.Mat, points *gocv.Mat, width, height int) (gocv.Mat, bool, string) {
-	dots, imagePoints, err := detectDots(img, imgWithMiniCode, points, width, height)
-	if err != nil {
-		slog.Debug(fmt.Sprintf("No valid QR-code found in video frame: %v", err))
-		return *img, false, ""
+// logResult logs the metadata of the decoded QR-code.
+func logResult(result qrcode.Result) {
+	if len(result.Points) > 0 {
+		slog.Info(fmt.Sprintf("QR-code found at %s, corners %v", position(result.Points), result.Points))
 	}
-	slog.Info("Dots scanned successfully, proceed")
-
-	bits, version, errorCorrectionLevel, err := extractBits(dots)
-	if err != nil {
-		slog.Warn(fmt.Sprintf("Dots do not form a valid QR-code: %v", err))
-		return *img, false, ""
+	slog.Info(fmt.Sprintf("Version is %d / Mask ID is %d / Error correction level is %s",
+		result.Version, result.Mask, result.ErrorCorrectionLevel.String()))
+	if result.Mirrored {
+		slog.Info("QR-code is mirrored")
 	}
-	slog.Info("Bits extracted successfully, proceed")
-
-	message, err := decodeMessage(bits, version, errorCorrectionLevel)
-	if err != nil {
-		slog.Warn(fmt.Sprintf("QR-code cannot be decoded: %v", err))
-		return *img, false, ""
+	if result.Inverted {
+		slog.Info("QR-code is light on dark")
 	}
-
-	// success
-	printQRCode(dots)
-	detect.OutlineQRCode(imgWithMiniCode, imagePoints, color.RGBA{255, 0, 0, 255}, 5)
-
-	return *imgWithMiniCode, true, message
-}
-
-// detectDots detects the QR-code location from the given image (video frame),
-// then extracts the QR-code dots from the image.
-func detectDots(img, imgWithMiniCode *gocv.Mat, points *gocv.Mat, width, height int) (detect.QRCode, []image.Point, error) {
-	qrcodeDetector := gocv.NewQRCodeDetector()
-	found := qrcodeDetector.Detect(*img, points) // false positives
-	if !found {
-		return nil, nil, errors.New("no QR-code detected in image")
+	if result.FormatRecovered {
+		slog.Info("Format information is unreadable, recovered from the contents")
 	}
-
-	imagePoints := newImagePointsFromPoints(points)
-
-	valid := detect.ValidateSquare(imagePoints, width, height)
-	if !valid {
-		return nil, nil, errors.New("detected QR-code is not a square")
+	if result.SoftDots != nil {
+		slog.Info(fmt.Sprintf("%d dots read with low confidence", result.SoftDots.Doubtful(qrcode.LowConfidence)))
 	}
-
-	img.CopyTo(imgWithMiniCode)
-	miniCode := detect.SetMiniCodeInCorner(imgWithMiniCode, imagePoints, miniCodeWidth, miniCodeHeight)
-	detect.EnhanceImage(&miniCode)
-
-	dots, ok := detect.GetDots(miniCode)
-	miniCode.Close()
-	if !ok {
-		return nil, nil, errors.New("detected pixels do not contain QR-code dots")
+	if header := result.StructuredAppend; header != nil {
+		slog.Info(fmt.Sprintf("Structured append: QR-code %d/%d, parity %02x", header.Index+1, header.Total, header.Parity))
 	}
-
-	return dots, imagePoints, nil
-}
-
-// extractBits follows explanations from https://typefully.com/DanHollick/qr-codes-T7tLlNi
-// to extract the QR-code bits from the 2D dots grid.
-func extractBits(dots detect.QRCode) ([]bool, uint, decode.ErrorCorrectionLevel, error) {
-	if len(dots) < 17 {
-		return nil, 0, 0, errors.New("dots array too small")
+	for i, segment := range result.Segments {
+		slog.Info(fmt.Sprintf("Segment %d: mode is %s / Content length is %d characters", i+1, segment.Mode.String(), segment.Length))
 	}
 
-	version, err := extract.Version(dots)
-	if err != nil {
-		return nil, 0, 0, err
-	}
-	maskID, errorCorrectionLevel, err := extract.Format(dots)
-	if err != nil {
-		return nil, 0, 0, err
+	if result.IsGS1() {
+		elements, err := result.GS1Elements()
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Invalid GS1 data: %v", err))
+		}
+		for _, element := range elements {
+			slog.Info(fmt.Sprintf("GS1 (%s) %s: %s", element.AI, element.Title, element.Value))
+		}
 	}
-	slog.Info(fmt.Sprintf("Mask ID is %d / Error correction level is %s", maskID, errorCorrectionLevel.String()))
-
-	bits := extract.ReadBits(dots, maskID)
-
-	return bits, version, errorCorrectionLevel, nil
 }
 
-// decodeMessages performs error correction on the bits read, then decodes the message.
-// In case error correction fails, the uncorrected message is returned (if possible).
-func decodeMessage(bits []bool, version uint, errorCorrectionLevel decode.ErrorCorrectionLevel) (string, error) {
-	bitsCorrected, err := decode.Correct(bits, version, errorCorrectionLevel)
-	if err != nil {
-		return "", err
+// position returns the center of the QR-code delimited by the given corners, formatted as "(x, y)".
+func position(points []image.Point) string {
+	if len(points) == 0 {
+		return "(?, ?)"
 	}
-
-	mode, bits := decode.GetMode(bitsCorrected)
-	length, contents, err := decode.GetContentLength(bits, version, mode, errorCorrectionLevel)
-	if err != nil {
-		return "", err
+	var center image.Point
+	for _, point := range points {
+		center = center.Add(point)
 	}
-	slog.Info(fmt.Sprintf("Mode is %s / Content length is %d bytes", mode.String(), length))
-
-	message, err := decode.Message(mode, length, contents)
-	if err != nil {
-		return "", err
-	}
-
-	return message, nil
+	center = center.Div(len(points))
+	return fmt.Sprintf("(%d, %d)", center.X, center.Y)
 }
@@ -0,0 +1,25 @@
+//go:build !cgo
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/benoitmasson/qrcode-demo/qrcode"
+)
+
+// Without cgo, OpenCV is not available: only image files may be decoded, and QR-codes generated.
+
+var errOpenCVRequired = errors.New("OpenCV is required, build with cgo enabled")
+
+func scanDevice(deviceID int, _ qrcode.Options) {
+	slog.Error(fmt.Sprintf("Cannot open video capture device %d: %v", deviceID, errOpenCVRequired))
+	os.Exit(1)
+}
+
+func decodeVideo(string, qrcode.Options) error {
+	return errOpenCVRequired
+}
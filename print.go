@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"strings"
 
-	"gocv.io/x/gocv"
-
 	"github.com/benoitmasson/qrcode-demo/internal/detect"
 )
 
@@ -31,23 +29,3 @@ func printQRCode(qrcode detect.QRCode) {
 
 	fmt.Println(strings.Repeat(" ", 2*len(qrcode[0])+3), "\033[0;m") // end with blank line, turn off inverse mode
 }
-
-// nolint: unused
-func printQRCodeMat(qrcode gocv.Mat) {
-	fmt.Println("\033[7;m", strings.Repeat(" ", 2*qrcode.Cols()+3)) // turn on inverse mode, start with blank line
-
-	for i := 0; i < qrcode.Rows(); i++ {
-		fmt.Print("  ") // start line with blank characters
-		for j := 0; j < qrcode.Cols(); j++ {
-			val := qrcode.GetUCharAt(i, j)
-			char := " " // blank, displayed white
-			if val == 0 {
-				char = "█" // filled, displayed black
-			}
-			fmt.Print(char, char) // double print to achieve 1:1 scale
-		}
-		fmt.Println("  ") // end line with blank characters
-	}
-
-	fmt.Println(strings.Repeat(" ", 2*qrcode.Cols()+3), "\033[0;m") // end with blank line, turn off inverse mode
-}
@@ -0,0 +1,190 @@
+//go:build cgo
+
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+
+	"gocv.io/x/gocv"
+
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+)
+
+const (
+	miniCodeWidth  = 200
+	miniCodeHeight = 200
+)
+
+// DecodeMat detects a QR-code in the given OpenCV image (e.g. a video frame), then decodes it, with the default options.
+func DecodeMat(img gocv.Mat) (Result, error) {
+	return DecodeMatWithOptions(img, DefaultOptions)
+}
+
+// DecodeMatWithOptions detects a QR-code in the given OpenCV image with the given options, then decodes it.
+func DecodeMatWithOptions(img gocv.Mat, options Options) (Result, error) {
+	dots, points, inverted, err := DetectDots(img, nil, options)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := DecodeDetected(dots, inverted)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Points = points
+
+	return result, nil
+}
+
+// DecodeAllMat detects all the QR-codes in the given OpenCV image (e.g. a video frame), then decodes them,
+// with the default options.
+func DecodeAllMat(img gocv.Mat) ([]Result, error) {
+	return DecodeAllMatWithOptions(img, DefaultOptions)
+}
+
+// DecodeAllMatWithOptions detects all the QR-codes in the given OpenCV image with the given options,
+// then decodes them independently. Codes which cannot be decoded are skipped: it fails only if none of them
+// can be decoded.
+func DecodeAllMatWithOptions(img gocv.Mat, options Options) ([]Result, error) {
+	codes, corners, inverted, err := DetectAllDots(img, nil, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeMatrices(codes, corners, inverted)
+}
+
+// DetectDots detects the QR-code location from the given image (e.g. a video frame),
+// then extracts the QR-code dots from the image, with the confidence of their reading.
+// If imgWithMiniCode is not nil, the image is copied into it, with the detected QR-code projected
+// in the top-left corner, upright. Returns the dots (the right way up, light dots being black for light-on-dark codes),
+// the QR-code corners in the image (clockwise, starting from the top-left corner of the code), and whether the code
+// is light on dark.
+func DetectDots(img gocv.Mat, imgWithMiniCode *gocv.Mat, options Options) (SoftMatrix, []image.Point, bool, error) {
+	if img.Cols() < miniCodeWidth || img.Rows() < miniCodeHeight {
+		return nil, nil, false, fmt.Errorf("image too small, should be at least %dx%d", miniCodeWidth, miniCodeHeight)
+	}
+
+	qrcodeDetector := gocv.NewQRCodeDetector()
+	defer qrcodeDetector.Close()
+	points := gocv.NewMat()
+	defer points.Close()
+
+	found := qrcodeDetector.Detect(img, &points) // false positives
+	if !found {
+		return nil, nil, false, errors.New("no QR-code detected in image")
+	}
+
+	imagePoints := newImagePointsFromPoints(&points)
+
+	valid := detect.ValidateQuadrilateral(imagePoints, img.Cols(), img.Rows(), options)
+	if !valid {
+		return nil, nil, false, errors.New("detected QR-code outline is too distorted")
+	}
+
+	if imgWithMiniCode == nil {
+		scratch := gocv.NewMat()
+		defer scratch.Close()
+		imgWithMiniCode = &scratch
+	}
+	img.CopyTo(imgWithMiniCode)
+
+	return scanMiniCode(img, imgWithMiniCode, imagePoints, image.Point{}, options)
+}
+
+// DetectAllDots detects the locations of all the QR-codes in the given image (e.g. a video frame),
+// then extracts the dots of each QR-code from the image, with the confidence of their reading.
+// If imgWithMiniCode is not nil, the image is copied into it, with the detected QR-codes projected
+// side by side from the top-left corner, upright. Returns the dots (the right way up), the corners in the image
+// and whether it is light on dark of each QR-code found, in the same order.
+func DetectAllDots(img gocv.Mat, imgWithMiniCode *gocv.Mat, options Options) ([]SoftMatrix, [][]image.Point, []bool, error) {
+	if img.Cols() < miniCodeWidth || img.Rows() < miniCodeHeight {
+		return nil, nil, nil, fmt.Errorf("image too small, should be at least %dx%d", miniCodeWidth, miniCodeHeight)
+	}
+
+	qrcodeDetector := gocv.NewQRCodeDetector()
+	defer qrcodeDetector.Close()
+	points := gocv.NewMat()
+	defer points.Close()
+
+	found := qrcodeDetector.DetectMulti(img, &points) // false positives
+	if !found {
+		return nil, nil, nil, errors.New("no QR-code detected in image")
+	}
+
+	if imgWithMiniCode == nil {
+		scratch := gocv.NewMat()
+		defer scratch.Close()
+		imgWithMiniCode = &scratch
+	}
+	img.CopyTo(imgWithMiniCode)
+
+	// each row holds the 4 corners of a QR-code
+	allPoints := newImagePointsFromPoints(&points)
+	var codes []SoftMatrix
+	var corners [][]image.Point
+	var inverted []bool
+	err := errors.New("no QR-code detected in image")
+	for i := 0; i+4 <= len(allPoints); i += 4 {
+		imagePoints := allPoints[i : i+4]
+		if !detect.ValidateQuadrilateral(imagePoints, img.Cols(), img.Rows(), options) {
+			err = errors.New("detected QR-code outline is too distorted")
+			continue
+		}
+
+		origin := image.Point{X: len(codes) * miniCodeWidth}
+		// codes are read from the original image: the mini-codes already pasted may cover the next ones
+		dots, imagePoints, codeInverted, scanErr := scanMiniCode(img, imgWithMiniCode, imagePoints, origin, options)
+		if scanErr != nil {
+			err = scanErr
+			continue
+		}
+		codes = append(codes, dots)
+		corners = append(corners, imagePoints)
+		inverted = append(inverted, codeInverted)
+	}
+
+	if len(codes) == 0 {
+		return nil, nil, nil, err
+	}
+	return codes, corners, inverted, nil
+}
+
+// scanMiniCode projects the QR-code delimited by the given points in the source image at the given origin
+// of the destination image, then extracts its dots with the binarization method given by the options.
+// Returns the dots, the QR-code corners (clockwise from the top-left corner of the code), and whether the code
+// is light on dark.
+func scanMiniCode(src gocv.Mat, dst *gocv.Mat, points []image.Point, origin image.Point, options Options) (SoftMatrix, []image.Point, bool, error) {
+	miniCode, points := detect.SetMiniCodeAt(src, dst, points, origin, miniCodeWidth, miniCodeHeight)
+	detect.EnhanceImage(&miniCode)
+
+	dots, inverted, ok := detect.GetDots(miniCode, options.Binarization)
+	miniCode.Close()
+	if !ok {
+		return nil, nil, false, errors.New("detected pixels do not contain QR-code dots")
+	}
+
+	return dots, points, inverted, nil
+}
+
+func newImagePointsFromPoints(points *gocv.Mat) []image.Point {
+	r, c := points.Rows(), points.Cols()
+	slog.Debug(fmt.Sprint("Matrix info: ", points.Channels(), points.Size(), points.Type(), points.Total(), r, c))
+
+	imagePoints := make([]image.Point, 0, r*c)
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			vec := points.GetVecfAt(i, j)
+			x, y := vec[0], vec[1]
+
+			imagePoints = append(imagePoints, image.Point{
+				X: int(x),
+				Y: int(y),
+			})
+		}
+	}
+	return imagePoints
+}
@@ -0,0 +1,439 @@
+// Package qrcode detects and decodes QR-codes from images, video frames or dots matrices.
+//
+// The decoding pipeline follows the explanations from https://typefully.com/DanHollick/qr-codes-T7tLlNi:
+// detect the code in the image and scan its dots, extract the metadata and the contents bits from the dots,
+// then correct and decode the contents bits.
+package qrcode
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"slices"
+
+	"github.com/benoitmasson/qrcode-demo/internal/decode"
+	"github.com/benoitmasson/qrcode-demo/internal/detect"
+	"github.com/benoitmasson/qrcode-demo/internal/extract"
+)
+
+// Matrix is the representation of the code: 2-dimensional array of dots, "true" meaning black dot.
+type Matrix = detect.QRCode
+
+type (
+	// SoftMatrix is the representation of the code as read from an image: each dot comes with the confidence
+	// of its reading.
+	SoftMatrix = detect.SoftQRCode
+	// SoftDot is a dot read from several samples of the image.
+	SoftDot = detect.SoftDot
+)
+
+// ErrorCorrectionLevel is the level of redundancy of the QR-code contents.
+type ErrorCorrectionLevel = decode.ErrorCorrectionLevel
+
+const (
+	ErrorCorrectionLevelLow      = decode.ErrorCorrectionLevelLow
+	ErrorCorrectionLevelMedium   = decode.ErrorCorrectionLevelMedium
+	ErrorCorrectionLevelQuartile = decode.ErrorCorrectionLevelQuartile
+	ErrorCorrectionLevelHigh     = decode.ErrorCorrectionLevelHigh
+)
+
+// MaskID identifies the mask applied to the QR-code contents dots, from 0 to 7.
+type MaskID = extract.MaskID
+
+// Mode is the encoding mode of a segment.
+type Mode = decode.Mode
+
+const (
+	NumericMode      = decode.NumericMode
+	AlphanumericMode = decode.AlphanumericMode
+	ByteMode         = decode.ByteMode
+	KanjiMode        = decode.KanjiMode
+)
+
+type (
+	// Segment is a part of the message, whose characters are all encoded with the same mode.
+	Segment = decode.Segment
+	// StructuredAppend is the header of a QR-code which holds one part of a message split across several QR-codes.
+	StructuredAppend = decode.StructuredAppend
+	// Sequence collects the parts of a message split across several QR-codes.
+	Sequence = decode.Sequence
+	// GS1Element is a single element string of GS1 data: an Application Identifier and its value.
+	GS1Element = decode.GS1Element
+)
+
+// NewSequence returns an empty sequence, for the QR-codes matching the given header.
+func NewSequence(header StructuredAppend) *Sequence {
+	return decode.NewSequence(header)
+}
+
+// Result holds the decoded contents of a QR-code, along with its metadata.
+type Result struct {
+	// Message is the decoded text.
+	Message string
+	// Bytes is the raw contents, before conversion to text.
+	Bytes []byte
+	// Version is the QR-code version, from 1 to 40.
+	Version uint
+	// ErrorCorrectionLevel is the level of redundancy of the contents.
+	ErrorCorrectionLevel ErrorCorrectionLevel
+	// Mask is the ID of the mask applied to the contents dots.
+	Mask MaskID
+	// Segments is the list of segments the message is made of.
+	Segments []Segment
+	// StructuredAppend is set when the QR-code holds only one part of a message.
+	StructuredAppend *StructuredAppend
+	// Dots is the dots matrix the message was decoded from (mirrored back and inverted back, if needed).
+	Dots Matrix
+	// SoftDots holds the dots as read from the image, with the confidence of each of them (mirrored back
+	// and inverted back, if needed). It is nil when decoded from a dots matrix.
+	SoftDots SoftMatrix
+	// Mirrored is set when the QR-code was a mirror image (e.g. seen through glass, or printed on a transparency).
+	Mirrored bool
+	// Inverted is set when the QR-code was light on dark (e.g. on a poster, or on a screen in dark mode).
+	Inverted bool
+	// FormatRecovered is set when the format information could not be read, and the mask and error correction
+	// level were found by trying all of them.
+	FormatRecovered bool
+	// Points are the QR-code corners in the image, when decoded from an image.
+	Points []image.Point
+}
+
+// IsGS1 returns whether the QR-code holds GS1 data.
+func (r Result) IsGS1() bool {
+	return decode.IsGS1(r.Segments)
+}
+
+// GS1Elements splits GS1 data into its element strings, and validates them.
+func (r Result) GS1Elements() ([]GS1Element, error) {
+	if !r.IsGS1() {
+		return nil, errors.New("QR-code does not hold GS1 data")
+	}
+	return decode.ParseGS1(r.Message)
+}
+
+// Options configure QR-code detection.
+type Options = detect.Options
+
+// Binarization is the method used to tell black pixels from white ones in images.
+type Binarization = detect.Binarization
+
+const (
+	BinarizationOtsu    = detect.BinarizationOtsu
+	BinarizationMean    = detect.BinarizationMean
+	BinarizationSauvola = detect.BinarizationSauvola
+)
+
+// DefaultOptions are the detection options used by Decode.
+var DefaultOptions = detect.DefaultOptions
+
+// Decode detects a QR-code in the given image, then decodes it, with the default options.
+// Detection is performed in pure Go, from the QR-code finder patterns.
+func Decode(img image.Image) (Result, error) {
+	return DecodeWithOptions(img, DefaultOptions)
+}
+
+// DecodeWithOptions detects a QR-code in the given image with the given options, then decodes it.
+// If the version information of the QR-code does not match the dimension its dots were sampled with,
+// they are sampled again with the decoded version.
+func DecodeWithOptions(img image.Image, options Options) (Result, error) {
+	result, err := decodeWithOptions(img, options)
+	var mismatch *extract.VersionMismatchError
+	if errors.As(err, &mismatch) && options.Version != mismatch.Information {
+		slog.Debug(fmt.Sprintf("QR-code sampled as version %d, sample it again as version %d", mismatch.Size, mismatch.Information))
+		options.Version = mismatch.Information
+		result, err = decodeWithOptions(img, options)
+	}
+	return result, err
+}
+
+// decodeWithOptions detects a QR-code in the given image with the given options, then decodes it once.
+func decodeWithOptions(img image.Image, options Options) (Result, error) {
+	softDots, points, inverted, err := detect.FindQRCode(img, options)
+	if err != nil {
+		return Result{}, fmt.Errorf("no valid QR-code found in image: %w", err)
+	}
+
+	result, err := DecodeDetected(softDots, inverted)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Points = points
+
+	return result, nil
+}
+
+// DecodeAll detects all the QR-codes in the given image, then decodes them, with the default options.
+func DecodeAll(img image.Image) ([]Result, error) {
+	return DecodeAllWithOptions(img, DefaultOptions)
+}
+
+// DecodeAllWithOptions detects all the QR-codes in the given image with the given options, then decodes them
+// independently. Codes which cannot be decoded are skipped: it fails only if none of them can be decoded.
+func DecodeAllWithOptions(img image.Image, options Options) ([]Result, error) {
+	codes, corners, inverted, err := detect.FindQRCodes(img, options)
+	if err != nil {
+		return nil, fmt.Errorf("no valid QR-code found in image: %w", err)
+	}
+
+	return decodeMatrices(codes, corners, inverted)
+}
+
+// decodeMatrices decodes each of the given dots matrices, as found in an image (the right way up) at the given
+// corners, light on dark or not. Matrices which cannot be decoded are skipped: it fails only if none of them
+// can be decoded.
+func decodeMatrices(codes []SoftMatrix, corners [][]image.Point, inverted []bool) ([]Result, error) {
+	results := make([]Result, 0, len(codes))
+	err := errors.New("no QR-code to decode")
+	for i, softDots := range codes {
+		result, decodeErr := DecodeDetected(softDots, inverted[i])
+		if decodeErr != nil {
+			slog.Debug(fmt.Sprintf("QR-code at %v cannot be decoded: %v", corners[i], decodeErr))
+			err = decodeErr
+			continue
+		}
+		result.Points = corners[i]
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DecodeSoftMatrix decodes the most likely dots of the given soft matrix, as DecodeMatrix does.
+// The confidence of the dots is used during error correction: codewords holding doubtful dots are considered
+// as erased if the message cannot be corrected otherwise. The soft matrix is kept in the result.
+func DecodeSoftMatrix(softDots SoftMatrix) (Result, error) {
+	if err := checkSquare(softDots); err != nil {
+		return Result{}, err
+	}
+	return decodeDots(softDots.Dots(), softDots, readings)
+}
+
+// DecodeDetected decodes the given soft matrix, as DecodeSoftMatrix does, knowing its dots were found in an image
+// the right way up (dark on light), the code being originally light on dark if inverted is set: the dots are only
+// read again as a mirror image when decoding fails.
+func DecodeDetected(softDots SoftMatrix, inverted bool) (Result, error) {
+	if err := checkSquare(softDots); err != nil {
+		return Result{}, err
+	}
+	result, err := decodeDots(softDots.Dots(), softDots, readings[:2])
+	if err != nil {
+		return Result{}, err
+	}
+	result.Inverted = inverted
+	return result, nil
+}
+
+// LowConfidence is the confidence below which dots read from an image are considered doubtful.
+const LowConfidence = 0.5
+
+// DecodeMatrix extracts the contents bits from the given dots matrix, then decodes them.
+// When this fails, the dots are read again as a mirror image of the QR-code, then with black and white dots
+// swapped (light-on-dark QR-code), and both.
+func DecodeMatrix(dots Matrix) (Result, error) {
+	if err := checkSquare(dots); err != nil {
+		return Result{}, err
+	}
+	return decodeDots(dots, nil, readings)
+}
+
+// checkSquare makes sure the given dots form a square matrix, at least as large as a version 1 QR-code,
+// before it is read in any way.
+func checkSquare[T any](dots [][]T) error {
+	if len(dots) < 21 {
+		return fmt.Errorf("dots array too small: %d rows, expected at least 21", len(dots))
+	}
+	for i, row := range dots {
+		if len(row) != len(dots) {
+			return fmt.Errorf("dots array is not square: row %d has %d dots, expected %d", i, len(row), len(dots))
+		}
+	}
+	return nil
+}
+
+// reading is a way to read a dots matrix: as a mirror image or not, with black and white dots swapped or not.
+type reading struct{ mirrored, inverted bool }
+
+// readings are the ways a raw dots matrix is read, in order, until its message is decoded.
+// Matrices found in images are the right way up: only the first two readings apply.
+var readings = []reading{
+	{false, false},
+	{true, false},
+	{false, true},
+	{true, true},
+}
+
+// decodeDots decodes the given dots matrix with each one of the given readings in turn (as is, as a mirror image,
+// or with black and white dots swapped), using the confidence of the dots when softDots is not nil.
+// The error returned is the one of the matrix read as is.
+func decodeDots(dots Matrix, softDots SoftMatrix, readings []reading) (Result, error) {
+	var err error
+	for _, reading := range readings {
+		readDots, readSoftDots := dots, softDots
+		if reading.inverted {
+			readDots = readDots.Invert()
+			if readSoftDots != nil {
+				readSoftDots = readSoftDots.Invert()
+			}
+		}
+		if reading.mirrored {
+			// once its finders are oriented, a mirrored QR-code is read transposed
+			readDots = readDots.Transpose()
+			if readSoftDots != nil {
+				readSoftDots = readSoftDots.Transpose()
+			}
+		}
+
+		result, readErr := decodeMatrix(readDots, readSoftDots)
+		if readErr != nil {
+			if err == nil {
+				err = readErr
+			}
+			continue
+		}
+		if reading.mirrored {
+			slog.Debug("QR-code is mirrored")
+		}
+		if reading.inverted {
+			slog.Debug("QR-code is light on dark")
+		}
+		result.Mirrored, result.Inverted = reading.mirrored, reading.inverted
+		return result, nil
+	}
+
+	return Result{}, err
+}
+
+// decodeMatrix extracts the contents bits from the given dots matrix, then decodes them.
+// If softDots is not nil, it holds the confidence of each dot, used for error correction.
+func decodeMatrix(dots Matrix, softDots SoftMatrix) (Result, error) {
+	version, formats, err := extractMetadata(dots)
+	if err != nil {
+		return Result{}, fmt.Errorf("dots do not form a valid QR-code: %w", err)
+	}
+	candidates, formatRecovered := formats[:1], false
+	if formats[1].Distance() <= extract.MaxFormatErrors {
+		// when the contents cannot be corrected, the format may have been misread: try the second best one,
+		// unless the format information rules it out
+		candidates = formats[:2]
+	}
+	if formats[0].Distance() > extract.MaxFormatErrors {
+		slog.Debug(fmt.Sprintf("Too many errors in format information (%d at least), try all formats", formats[0].Distance()))
+		candidates, err = recoverFormats(dots, version, formats)
+		if err != nil {
+			return Result{}, fmt.Errorf("dots do not form a valid QR-code: %w", err)
+		}
+		formatRecovered = true
+	}
+	slog.Debug("Metadata extracted successfully, proceed")
+
+	var confidences []float64
+	if softDots != nil {
+		confidences = extract.ReadConfidences(softDots)
+	}
+
+	for i, format := range candidates {
+		slog.Debug(fmt.Sprintf("Mask ID is %d / Error correction level is %s (%v errors in format information)",
+			format.MaskID, format.ErrorCorrectionLevel.String(), format.Distances))
+		bits := extract.ReadBits(dots, format.MaskID)
+
+		result, decodeErr := decodeMessage(bits, confidences, version, format.ErrorCorrectionLevel)
+		if decodeErr != nil {
+			if i == 0 {
+				err = decodeErr
+			}
+			continue
+		}
+		result.Mask = format.MaskID
+		result.FormatRecovered = formatRecovered
+		result.Dots = dots
+		result.SoftDots = softDots
+		return result, nil
+	}
+
+	return Result{}, fmt.Errorf("QR-code cannot be decoded: %w", err)
+}
+
+// extractMetadata follows explanations from https://typefully.com/DanHollick/qr-codes-T7tLlNi
+// to extract the QR-code version and format from the 2D dots grid.
+// The possible formats are ranked, the more likely first.
+func extractMetadata(dots Matrix) (uint, []extract.FormatCandidate, error) {
+	if len(dots) < 17 {
+		return 0, nil, errors.New("dots array too small")
+	}
+
+	version, err := extract.Version(dots)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return version, extract.Formats(dots), nil
+}
+
+// recoverFormats finds the possible formats of a QR-code whose format information is unreadable (e.g. both copies
+// are smudged): the contents bits are read and corrected with each one of the given formats (all 4 error correction
+// levels times 8 masks), and the formats for which correction succeeds are returned, those needing the fewest
+// corrections first.
+// With a wrong mask, Reed-Solomon decoding fails or spends most of the ECC symbols. With the right mask,
+// when two levels split the contents into the same blocks, the contents protected by the stronger level are valid
+// for the weaker one as well (its ECC symbols are a subset of the others): ties go to the stronger level,
+// then to the format ranked first.
+func recoverFormats(dots Matrix, version uint, formats []extract.FormatCandidate) ([]extract.FormatCandidate, error) {
+	type recovered struct {
+		format                  extract.FormatCandidate
+		corrections, eccSymbols int
+	}
+	var candidates []recovered
+	for _, format := range formats {
+		bits := extract.ReadBits(dots, format.MaskID)
+		_, corrections, err := decode.CorrectAndCount(bits, version, format.ErrorCorrectionLevel)
+		if err != nil {
+			continue
+		}
+		blockSizes, eccSymbols := decode.BlockSizes(version, format.ErrorCorrectionLevel)
+		candidates = append(candidates, recovered{format, corrections, eccSymbols * len(blockSizes)})
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("format information cannot be read, and contents cannot be corrected with any format")
+	}
+	slices.SortStableFunc(candidates, func(a, b recovered) int {
+		return cmp.Or(cmp.Compare(a.corrections, b.corrections), cmp.Compare(b.eccSymbols, a.eccSymbols))
+	})
+
+	recoveredFormats := make([]extract.FormatCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		slog.Debug(fmt.Sprintf("Contents corrected with mask ID %d / error correction level %s (%d corrections)",
+			candidate.format.MaskID, candidate.format.ErrorCorrectionLevel.String(), candidate.corrections))
+		recoveredFormats = append(recoveredFormats, candidate.format)
+	}
+	return recoveredFormats, nil
+}
+
+// decodeMessage performs error correction on the bits read, knowing the confidence of each of them (if not nil),
+// then decodes the message.
+func decodeMessage(bits []bool, confidences []float64, version uint, errorCorrectionLevel ErrorCorrectionLevel) (Result, error) {
+	bitsCorrected, err := decode.CorrectWithConfidence(bits, confidences, LowConfidence, version, errorCorrectionLevel)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Version: version, ErrorCorrectionLevel: errorCorrectionLevel}
+	header, bitsCorrected, found := decode.GetStructuredAppend(bitsCorrected)
+	if found {
+		result.StructuredAppend = &header
+	}
+
+	result.Message, result.Segments, err = decode.Segments(bitsCorrected, version, errorCorrectionLevel)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, segment := range result.Segments {
+		result.Bytes = append(result.Bytes, segment.Bytes...)
+	}
+
+	return result, nil
+}
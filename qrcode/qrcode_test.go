@@ -0,0 +1,573 @@
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/benoitmasson/qrcode-demo/internal/encode"
+	"github.com/benoitmasson/qrcode-demo/internal/extract"
+	"github.com/benoitmasson/qrcode-demo/internal/render"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		level      ErrorCorrectionLevel
+		moduleSize float64
+		version    uint
+	}{
+		{
+			name:       "version 1",
+			text:       "HELLO WORLD",
+			moduleSize: 4,
+			version:    1,
+		},
+		{
+			name:       "version 4, fractional module size",
+			text:       "https://github.com/benoitmasson/qrcode-demo",
+			moduleSize: 3.7,
+			version:    4,
+		},
+		{
+			name:       "version 9, with version information",
+			text:       strings.Repeat("QR-code demo ", 12),
+			moduleSize: 5.2,
+			version:    9,
+		},
+		{
+			name:       "version 20, small dots",
+			text:       strings.Repeat("QR-code demo ", 50),
+			moduleSize: 2.5,
+			version:    20,
+		},
+		{
+			name:       "version 22, tiny dots",
+			text:       strings.Repeat("QR-code demo ", 72),
+			level:      ErrorCorrectionLevelLow,
+			moduleSize: 2,
+			version:    22,
+		},
+		{
+			name:       "version 32",
+			text:       strings.Repeat("QR-code demo ", 112),
+			level:      ErrorCorrectionLevelMedium,
+			moduleSize: 3,
+			version:    32,
+		},
+		{
+			name:       "version 40",
+			text:       strings.Repeat("QR-code demo ", 217),
+			level:      ErrorCorrectionLevelLow,
+			moduleSize: 3,
+			version:    40,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := newTestImage(t, test.text, test.level, test.moduleSize)
+
+			result, err := Decode(img)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Message != test.text {
+				t.Errorf("expected message to equal %q but got %q", test.text, result.Message)
+			}
+			if result.Version != test.version {
+				t.Errorf("expected version %d but got %d", test.version, result.Version)
+			}
+			if len(result.Points) != 4 {
+				t.Errorf("expected 4 corners but got %v", result.Points)
+			}
+			if len(result.SoftDots) != len(result.Dots) {
+				t.Fatalf("expected %d lines of soft dots but got %d", len(result.Dots), len(result.SoftDots))
+			}
+			if doubtful := result.SoftDots.Doubtful(LowConfidence); doubtful > 0 {
+				t.Errorf("expected all dots to be read with confidence but got %d doubtful dots", doubtful)
+			}
+		})
+	}
+}
+
+func TestDecode_Transformed(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform transform
+	}{
+		{name: "rotated by 30 degrees", transform: transform{angle: 30}},
+		{name: "rotated by 45 degrees", transform: transform{angle: 45}},
+		{name: "rotated by 90 degrees", transform: transform{angle: 90}},
+		{name: "upside down", transform: transform{angle: 180}},
+		{name: "rotated by 270 degrees", transform: transform{angle: 270}},
+		{name: "tilted", transform: transform{tilt: 0.4}},
+		{name: "rotated and tilted", transform: transform{angle: 135, tilt: -0.3}},
+		{name: "mirrored", transform: transform{mirrored: true}},
+		{name: "mirrored and rotated", transform: transform{angle: 60, mirrored: true}},
+	}
+
+	text := "https://github.com/benoitmasson/qrcode-demo"
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := newTransformedTestImage(t, text, 5, test.transform)
+
+			result, err := Decode(img)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Message != text {
+				t.Errorf("expected message to equal %q but got %q", text, result.Message)
+			}
+			if result.Mirrored != test.transform.mirrored {
+				t.Errorf("expected mirrored to be %t but got %t", test.transform.mirrored, result.Mirrored)
+			}
+			if !result.Dots[0][0] || result.Dots[0][7] {
+				t.Errorf("expected dots to be returned the right way up, with a black finder and white separator")
+			}
+		})
+	}
+}
+
+func TestDecode_Inverted(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform transform
+	}{
+		{name: "light on dark", transform: transform{inverted: true}},
+		{name: "light on dark, rotated", transform: transform{angle: 120, inverted: true}},
+		{name: "light on dark, mirrored", transform: transform{mirrored: true, inverted: true}},
+	}
+
+	text := "https://github.com/benoitmasson/qrcode-demo"
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := newTransformedTestImage(t, text, 5, test.transform)
+
+			result, err := Decode(img)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Message != text {
+				t.Errorf("expected message to equal %q but got %q", text, result.Message)
+			}
+			if !result.Inverted {
+				t.Errorf("expected QR-code to be reported as inverted")
+			}
+			if result.Mirrored != test.transform.mirrored {
+				t.Errorf("expected mirrored to be %t but got %t", test.transform.mirrored, result.Mirrored)
+			}
+		})
+	}
+}
+
+func TestDecode_LensDistortion(t *testing.T) {
+	// sampled at regular intervals, the dots drift away from their centers far from the finders
+	text := strings.Repeat("QR-code demo ", 12) // version 9, with 6 alignment patterns
+	tests := []struct {
+		name      string
+		transform transform
+	}{
+		{name: "barrel distortion", transform: transform{distortion: 0.06}},
+		{name: "rotated with barrel distortion", transform: transform{angle: 30, distortion: 0.06}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := newTransformedTestImage(t, text, 5, test.transform)
+
+			result, err := Decode(img)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Message != text {
+				t.Errorf("expected message to equal %q but got %q", text, result.Message)
+			}
+		})
+	}
+}
+
+func TestDecodeWithOptions_TooDistorted(t *testing.T) {
+	img := newTransformedTestImage(t, "HELLO WORLD", 5, transform{tilt: 0.4})
+
+	_, err := DecodeWithOptions(img, Options{MaxAngleDeviation: 5, MaxSideRatio: 1.1})
+	if err == nil {
+		t.Errorf("expected error for QR-code too distorted")
+	}
+}
+
+func TestDecodeWithOptions_WrongVersion(t *testing.T) {
+	text := strings.Repeat("QR-code demo ", 50)
+	img := newTestImage(t, text, ErrorCorrectionLevelMedium, 3)
+
+	// the dots are first sampled with a wrong dimension, then again with the version read from the code
+	options := DefaultOptions
+	options.Version = 19
+	result, err := DecodeWithOptions(img, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Message != text {
+		t.Errorf("expected message to equal %q but got %q", text, result.Message)
+	}
+	if result.Version != 20 {
+		t.Errorf("expected version 20 but got %d", result.Version)
+	}
+}
+
+func TestDecodeMatrix_Mirrored(t *testing.T) {
+	text := strings.Repeat("QR-code demo ", 12) // version 11, with version information
+	dots, err := encode.Encode(text, ErrorCorrectionLevelQuartile)
+	if err != nil {
+		t.Fatalf("failed to encode text: %v", err)
+	}
+
+	result, err := DecodeMatrix(dots.Transpose())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Message != text {
+		t.Errorf("expected message to equal %q but got %q", text, result.Message)
+	}
+	if !result.Mirrored {
+		t.Errorf("expected QR-code to be reported as mirrored")
+	}
+	if !reflect.DeepEqual(result.Dots, dots) {
+		t.Errorf("expected dots to be mirrored back")
+	}
+}
+
+func TestDecodeMatrix_Invalid(t *testing.T) {
+	ragged := make(Matrix, 21)
+	for i := range ragged {
+		ragged[i] = make([]bool, 21-i%2)
+	}
+
+	tests := []struct {
+		name string
+		dots Matrix
+	}{
+		{name: "nil matrix", dots: nil},
+		{name: "too small", dots: Matrix{{true}}},
+		{name: "ragged matrix", dots: ragged},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DecodeMatrix(test.dots); err == nil {
+				t.Errorf("expected an error but got none")
+			}
+		})
+	}
+}
+
+func TestDecodeMatrix_Inverted(t *testing.T) {
+	text := "HELLO WORLD"
+	dots, err := encode.Encode(text, ErrorCorrectionLevelHigh)
+	if err != nil {
+		t.Fatalf("failed to encode text: %v", err)
+	}
+
+	result, err := DecodeMatrix(dots.Invert())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Message != text {
+		t.Errorf("expected message to equal %q but got %q", text, result.Message)
+	}
+	if !result.Inverted || result.Mirrored {
+		t.Errorf("expected QR-code to be reported as inverted only, got inverted %t and mirrored %t", result.Inverted, result.Mirrored)
+	}
+	if !reflect.DeepEqual(result.Dots, dots) {
+		t.Errorf("expected dots to be inverted back")
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	texts := []string{"HELLO WORLD", "https://github.com/benoitmasson/qrcode-demo", strings.Repeat("QR-code demo ", 12)}
+
+	// draw the QR-codes side by side, rotated differently
+	images := make([]image.Image, 0, len(texts))
+	width, height := 0, 0
+	for i, text := range texts {
+		img := newTransformedTestImage(t, text, 4, transform{angle: float64(20 * i)})
+		images = append(images, img)
+		width += img.Bounds().Dx()
+		height = max(height, img.Bounds().Dy())
+	}
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Gray{Y: 160}), image.Point{}, draw.Src)
+	x := 0
+	for _, codeImg := range images {
+		draw.Draw(img, codeImg.Bounds().Add(image.Point{X: x}), codeImg, image.Point{}, draw.Src)
+		x += codeImg.Bounds().Dx()
+	}
+
+	results, err := DecodeAll(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(texts) {
+		t.Fatalf("expected %d QR-codes but got %d", len(texts), len(results))
+	}
+	messages := make([]string, 0, len(results))
+	for _, result := range results {
+		messages = append(messages, result.Message)
+		if len(result.Points) != 4 {
+			t.Errorf("expected 4 corners but got %v", result.Points)
+		}
+	}
+	slices.Sort(messages)
+	slices.Sort(texts)
+	if !reflect.DeepEqual(messages, texts) {
+		t.Errorf("expected messages to equal %q but got %q", texts, messages)
+	}
+}
+
+func TestDecode_DimLight(t *testing.T) {
+	text := "HELLO WORLD"
+	img := newTestImage(t, text, ErrorCorrectionLevelMedium, 4).(*image.Gray)
+	for i, luminance := range img.Pix {
+		img.Pix[i] = 20 + luminance/5 // black is 20, white is 71, background is 52
+	}
+
+	result, err := Decode(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Message != text {
+		t.Errorf("expected message to equal %q but got %q", text, result.Message)
+	}
+}
+
+func TestDecodeWithOptions_UnevenLighting(t *testing.T) {
+	text := "https://github.com/benoitmasson/qrcode-demo"
+	img := newTransformedTestImage(t, text, 5, transform{angle: 10}).(*image.Gray)
+
+	// cast a shadow with a soft edge across the code
+	width := img.Bounds().Dx()
+	for y := range img.Bounds().Dy() {
+		for x := range width {
+			light := min(1, max(0.25, 0.25+0.75*float64(x-width/3)/float64(width/8)))
+			img.Pix[y*img.Stride+x] = uint8(float64(img.Pix[y*img.Stride+x]) * light)
+		}
+	}
+
+	for _, method := range []Binarization{BinarizationMean, BinarizationSauvola} {
+		t.Run(method.String(), func(t *testing.T) {
+			options := DefaultOptions
+			options.Binarization = method
+
+			result, err := DecodeWithOptions(img, options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Message != text {
+				t.Errorf("expected message to equal %q but got %q", text, result.Message)
+			}
+		})
+	}
+}
+
+func TestDecodeMatrix_SecondBestFormat(t *testing.T) {
+	text := "https://github.com/benoitmasson/qrcode-demo"
+	dots, err := encode.Encode(text, ErrorCorrectionLevelQuartile)
+	if err != nil {
+		t.Fatalf("failed to encode text: %v", err)
+	}
+	expected, err := DecodeMatrix(dots)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the top-left copy reads as another valid format, the other one has 2 errors: the wrong format ranks first
+	wrongMask := (expected.Mask + 1) % 8
+	extract.WriteFormatInformation(dots,
+		extract.FormatInformation(wrongMask, ErrorCorrectionLevelLow),
+		extract.FormatInformation(expected.Mask, ErrorCorrectionLevelQuartile)^0b000100000000100,
+	)
+
+	result, err := DecodeMatrix(dots)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Message != text {
+		t.Errorf("expected message to equal %q but got %q", text, result.Message)
+	}
+	if result.Mask != expected.Mask || result.ErrorCorrectionLevel != ErrorCorrectionLevelQuartile {
+		t.Errorf("expected mask %d / level %s but got mask %d / level %s", expected.Mask, ErrorCorrectionLevelQuartile, result.Mask, result.ErrorCorrectionLevel)
+	}
+}
+
+func TestDecodeMatrix_FormatRecovered(t *testing.T) {
+	text := "https://github.com/benoitmasson/qrcode-demo"
+
+	for _, level := range []ErrorCorrectionLevel{ErrorCorrectionLevelLow, ErrorCorrectionLevelMedium, ErrorCorrectionLevelQuartile, ErrorCorrectionLevelHigh} {
+		t.Run(level.String(), func(t *testing.T) {
+			dots, err := encode.Encode(text, level)
+			if err != nil {
+				t.Fatalf("failed to encode text: %v", err)
+			}
+			expected, err := DecodeMatrix(dots)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// both copies are covered: the top-left one with white, the other one with black
+			extract.WriteFormatInformation(dots, 0, 0b111111111111111)
+			if distance := extract.Formats(dots)[0].Distance(); distance <= extract.MaxFormatErrors {
+				t.Fatalf("expected format information to be unreadable, but the best format has %d errors", distance)
+			}
+
+			result, err := DecodeMatrix(dots)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Message != text {
+				t.Errorf("expected message to equal %q but got %q", text, result.Message)
+			}
+			// the encoder may raise the level, when the stronger one fits in the same version
+			if result.Mask != expected.Mask || result.ErrorCorrectionLevel != expected.ErrorCorrectionLevel {
+				t.Errorf("expected mask %d / level %s but got mask %d / level %s", expected.Mask, expected.ErrorCorrectionLevel, result.Mask, result.ErrorCorrectionLevel)
+			}
+			if !result.FormatRecovered || result.Mirrored || result.Inverted {
+				t.Errorf("expected QR-code to be reported as format recovered only, got format recovered %t, mirrored %t and inverted %t",
+					result.FormatRecovered, result.Mirrored, result.Inverted)
+			}
+		})
+	}
+}
+
+func TestDecodeSoftMatrix_Erasures(t *testing.T) {
+	text := "HELLO WORLD" // version 1-Q: 13 ECC codewords, correcting 6 errors or 12 erasures
+	dots, err := encode.Encode(text, ErrorCorrectionLevelQuartile)
+	if err != nil {
+		t.Fatalf("failed to encode text: %v", err)
+	}
+
+	// smudge the bottom-right corner: dots are inverted, and read with a low confidence
+	size := len(dots)
+	softDots := make(SoftMatrix, size)
+	for i := range softDots {
+		softDots[i] = make([]SoftDot, size)
+		for j := range softDots[i] {
+			darkness := 0.
+			if dots[i][j] {
+				darkness = 1
+			}
+			softDots[i][j] = SoftDot{Darkness: darkness, Confidence: 1}
+			if i >= size-8 && j >= size-8 {
+				softDots[i][j] = SoftDot{Darkness: 0.6 - 0.2*darkness, Confidence: 0.2}
+			}
+		}
+	}
+
+	if _, err := DecodeMatrix(softDots.Dots()); err == nil {
+		t.Fatalf("expected an error without the dots confidence")
+	}
+	result, err := DecodeSoftMatrix(softDots)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Message != text {
+		t.Errorf("expected message to equal %q but got %q", text, result.Message)
+	}
+	if result.Version != 1 || result.ErrorCorrectionLevel != ErrorCorrectionLevelQuartile {
+		t.Errorf("expected version 1-Q but got version %d-%s", result.Version, result.ErrorCorrectionLevel)
+	}
+}
+
+func TestDecode_NoCode(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 200, 200))
+	if _, err := Decode(img); err == nil {
+		t.Errorf("expected error for image without QR-code")
+	}
+}
+
+// newTestImage encodes the text with the given error correction level, and draws the QR-code with the given dot size in pixels,
+// on a gray background larger than the code.
+func newTestImage(t *testing.T, text string, level ErrorCorrectionLevel, moduleSize float64) image.Image {
+	t.Helper()
+
+	dots, err := encode.Encode(text, level)
+	if err != nil {
+		t.Fatalf("failed to encode text: %v", err)
+	}
+	return drawTestImage(t, dots, moduleSize, transform{})
+}
+
+// transform describes how the QR-code is seen in test images.
+type transform struct {
+	// angle is the clockwise rotation of the code, in degrees.
+	angle float64
+	// tilt is the perspective factor: the bottom of the code is farther than its top when positive.
+	tilt float64
+	// mirrored flips the code horizontally, before rotation.
+	mirrored bool
+	// distortion is the radial distortion of the lens: the edges of the code are closer to its center than they
+	// should be when positive (barrel distortion), farther when negative (pincushion distortion).
+	distortion float64
+	// inverted draws the code light on dark, on a dark background.
+	inverted bool
+}
+
+// newTransformedTestImage encodes the text, and draws the QR-code with the given dot size in pixels,
+// transformed as requested, on a gray background larger than the code. The image is an *image.Gray.
+func newTransformedTestImage(t *testing.T, text string, moduleSize float64, transform transform) image.Image {
+	t.Helper()
+
+	dots, err := encode.Encode(text, ErrorCorrectionLevelMedium)
+	if err != nil {
+		t.Fatalf("failed to encode text: %v", err)
+	}
+	return drawTestImage(t, dots, moduleSize, transform)
+}
+
+// drawTestImage draws the QR-code with the given dot size in pixels, transformed as requested,
+// on a gray background larger than the code. The image is an *image.Gray.
+func drawTestImage(t *testing.T, dots Matrix, moduleSize float64, transform transform) image.Image {
+	t.Helper()
+
+	code, err := render.Image(dots, render.Options{ModuleSize: 1, QuietZone: 4})
+	if err != nil {
+		t.Fatalf("failed to render QR-code: %v", err)
+	}
+
+	codeSize := float64(code.Bounds().Dx()) * moduleSize
+	imgSize := int(1.8 * codeSize)
+	img := image.NewGray(image.Rect(0, 0, imgSize+imgSize/3, imgSize))
+	sin, cos := math.Sincos(transform.angle * math.Pi / 180)
+	for y := range img.Bounds().Dy() {
+		for x := range img.Bounds().Dx() {
+			// map each pixel of the image back to the code
+			dx, dy := float64(x)-float64(img.Bounds().Dx())/2, float64(y)-float64(img.Bounds().Dy())/2
+			radial := 1 + transform.distortion*(dx*dx+dy*dy)/(codeSize*codeSize/4)
+			dx, dy = dx*radial, dy*radial
+			rx, ry := dx*cos+dy*sin, -dx*sin+dy*cos
+			w := 1 - transform.tilt*ry/codeSize
+			u, v := rx/w+codeSize/2, ry/w+codeSize/2
+			if transform.mirrored {
+				u = codeSize - u
+			}
+
+			if u < 0 || v < 0 || u >= codeSize || v >= codeSize || w <= 0 {
+				img.SetGray(x, y, color.Gray{Y: 160})
+				if transform.inverted {
+					img.SetGray(x, y, color.Gray{Y: 60})
+				}
+				continue
+			}
+			img.Set(x, y, code.At(int(u/moduleSize), int(v/moduleSize)))
+			if transform.inverted {
+				img.SetGray(x, y, color.Gray{Y: 255 - img.GrayAt(x, y).Y})
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/benoitmasson/qrcode-demo/qrcode"
+)
+
+// partsCollector gathers, across frames, the QR-codes holding the parts of a message
+// split with structured append.
+type partsCollector struct {
+	sequence *qrcode.Sequence
+}
+
+// collect returns the full message held by the decoded QR-code, and whether it is complete.
+// When the QR-code holds only one part of the message, the part is stored (a new sequence is started
+// if it does not belong to the current one), and the full message is returned only once all parts
+// have been collected and the parity is verified.
+func (c *partsCollector) collect(result qrcode.Result) (string, bool) {
+	header := result.StructuredAppend
+	if header == nil {
+		return result.Message, true
+	}
+
+	if c.sequence == nil || !c.sequence.Matches(*header) {
+		slog.Info(fmt.Sprintf("New structured append sequence of %d QR-codes", header.Total))
+		c.sequence = qrcode.NewSequence(*header)
+	}
+	added, err := c.sequence.Add(*header, result.Segments)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("QR-code cannot be added to sequence: %v", err))
+		return "", false
+	}
+	if !added {
+		return "", false
+	}
+	slog.Warn(fmt.Sprintf("Structured append: QR-code %d/%d collected (%d/%d)",
+		header.Index+1, header.Total, c.sequence.Collected(), c.sequence.Total()))
+
+	if !c.sequence.Complete() {
+		return "", false
+	}
+	message, err := c.sequence.Message()
+	c.sequence = nil
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Structured append message cannot be reassembled: %v", err))
+		return "", false
+	}
+	return message, true
+}
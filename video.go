@@ -0,0 +1,67 @@
+//go:build cgo
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gocv.io/x/gocv"
+
+	"github.com/benoitmasson/qrcode-demo/qrcode"
+)
+
+// decodeVideo reads all the frames of the given video file (MP4, AVI, MKV, …) without displaying them,
+// and prints every distinct message decoded on the standard output, with the frame number, timestamp
+// and position where it was first found. Several QR-codes may be decoded in the same frame.
+// It returns an error if the file cannot be read, or if no QR-code was found in the video.
+func decodeVideo(path string, options qrcode.Options) error {
+	video, err := gocv.VideoCaptureFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to open video: %w", err)
+	}
+	defer video.Close()
+
+	img := gocv.NewMat()
+	defer img.Close()
+
+	fps := video.Get(gocv.VideoCaptureFPS)
+	slog.Info(fmt.Sprintf("Start reading video %s: %.0f frames, %.2ffps", path, video.Get(gocv.VideoCaptureFrameCount), fps))
+
+	return printVideoMessages(&captureFrames{video: video, img: &img, fps: fps, options: options})
+}
+
+// captureFrames reads the frames of a video file with OpenCV, and decodes them with the given options.
+type captureFrames struct {
+	video   *gocv.VideoCapture
+	img     *gocv.Mat
+	fps     float64
+	options qrcode.Options
+	// frame is the number of frames read so far.
+	frame int
+}
+
+func (f *captureFrames) next() ([]qrcode.Result, time.Duration, bool, error) {
+	if !f.video.Read(f.img) {
+		return nil, 0, false, nil
+	}
+	f.frame++
+	if f.img.Empty() {
+		return nil, 0, true, nil
+	}
+	timestamp := frameTimestamp(f.video, f.frame, f.fps)
+
+	results, err := qrcode.DecodeAllMatWithOptions(*f.img, f.options)
+	return results, timestamp, true, err
+}
+
+// frameTimestamp returns the position of the frame just read in the video.
+// Some containers do not report positions, in which case it is computed from the frame rate.
+func frameTimestamp(video *gocv.VideoCapture, frame int, fps float64) time.Duration {
+	msec := video.Get(gocv.VideoCapturePosMsec)
+	if msec <= 0 && fps > 0 {
+		msec = float64(frame-1) * 1000 / fps
+	}
+	return time.Duration(msec * float64(time.Millisecond)).Round(time.Millisecond)
+}